@@ -0,0 +1,248 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// APIConfig configures the optional HTTP API of the widget.
+type APIConfig struct {
+	// Listen is either "host:port" with a loopback host (e.g. "localhost:7070")
+	// or the path of a Unix socket prefixed with "unix:". The API is disabled
+	// if it is empty.
+	Listen string `yaml:"listen,omitempty"`
+}
+
+func (c APIConfig) check(ch *configChecker) {
+	if c.Listen == "" || strings.HasPrefix(c.Listen, "unix:") {
+		return
+	}
+
+	host, _, err := net.SplitHostPort(c.Listen)
+	if err != nil {
+		ch.add("api.listen", "invalid address %q: %v", c.Listen, err)
+		return
+	}
+
+	if !isLoopback(host) {
+		ch.add("api.listen", "invalid address %q: only localhost is allowed", c.Listen)
+	}
+}
+
+func (c APIConfig) listen() (net.Listener, error) {
+	if strings.HasPrefix(c.Listen, "unix:") {
+		return listenUnix(strings.TrimPrefix(c.Listen, "unix:"))
+	}
+
+	return net.Listen("tcp", c.Listen)
+}
+
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// serveAPI starts the HTTP API in the background.
+func (app *App) serveAPI() (*http.Server, error) {
+	l, err := app.conf.API.listen()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open API listener")
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", app.handleStatus)
+	mux.HandleFunc("/days", app.handleDays)
+	mux.HandleFunc("/checkout", app.handleCheckOut)
+	mux.HandleFunc("/break", app.handleBreak)
+	mux.HandleFunc("/metrics", app.handleMetrics)
+
+	tcp := !strings.HasPrefix(app.conf.API.Listen, "unix:")
+	srv := &http.Server{
+		Handler:      apiMiddleware(mux, tcp),
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		err := srv.Serve(l)
+		if err != http.ErrServerClosed {
+			app.logger.Error("HTTP API stopped", zap.Error(err))
+		}
+	}()
+
+	app.logger.Info("Serving HTTP API", zap.String("address", app.conf.API.Listen))
+	return srv, nil
+}
+
+// apiMiddleware protects the API against requests from web sites. Browsers
+// cannot send JSON to another origin without a CORS preflight request (which
+// we never allow) and checking the Host header prevents DNS rebinding.
+func apiMiddleware(next http.Handler, checkHost bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if checkHost {
+			host, _, err := net.SplitHostPort(r.Host)
+			if err != nil {
+				host = r.Host
+			}
+			if !isLoopback(host) {
+				writeError(w, http.StatusForbidden, errors.New("invalid host"))
+				return
+			}
+		}
+
+		// This also applies to empty bodies since a web site can send those
+		// without a content type and thus without a preflight request.
+		if r.Method == http.MethodPost && !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+			writeError(w, http.StatusUnsupportedMediaType, errors.New("content type must be application/json, even if the body is empty"))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// GET /status returns the Status of the current day.
+func (app *App) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	var status Status
+	err := app.do(func() error {
+		status = NewStatus(app.conf, time.Now())
+		return nil
+	})
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, status)
+}
+
+// GET /days?from=YYYY-MM-DD&to=YYYY-MM-DD returns the recorded days just like
+// "go-home report --output json". Without parameters the current week is
+// returned.
+func (app *App) handleDays(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	var (
+		cal   Calendar
+		today time.Time
+	)
+	err := app.do(func() error {
+		cal, today = app.conf.calendar, app.conf.WorkDate(time.Now())
+		return nil
+	})
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+
+	opts := reportOptions{from: r.URL.Query().Get("from"), to: r.URL.Query().Get("to")}
+	from, to, err := opts.dateRange(today)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	days, err := app.journal.Days()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, errors.Wrap(err, "failed to read journal"))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, reportEntries(days, cal, from, to, today))
+}
+
+// POST /checkout with an optional body like {"at": "17:30"} finishes the
+// current day. Without a time, the day is finished now.
+func (app *App) handleCheckOut(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		At string `json:"at"`
+	}
+
+	app.handleCorrection(w, r, &req, func(conf *Config, now time.Time) error {
+		return conf.correctCheckOut(req.At, now)
+	})
+}
+
+// POST /break with a body like {"start": "12:00", "end": "12:45"} adds a
+// break to the current day.
+func (app *App) handleBreak(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Start string `json:"start"`
+		End   string `json:"end"`
+	}
+
+	app.handleCorrection(w, r, &req, func(conf *Config, now time.Time) error {
+		return conf.addBreak(req.Start + "-" + req.End)
+	})
+}
+
+// handleCorrection decodes the request body into req and applies the
+// correction to the running widget. It responds with the updated Status.
+func (app *App) handleCorrection(w http.ResponseWriter, r *http.Request, req interface{}, fn func(conf *Config, now time.Time) error) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(req)
+	if err != nil && err != io.EOF { // an empty body is fine
+		writeError(w, http.StatusBadRequest, errors.Wrap(err, "invalid request body"))
+		return
+	}
+
+	var (
+		invalid error
+		status  Status
+	)
+	err = app.do(func() error {
+		var err error
+		invalid, err = app.applyCorrection(fn)
+		status = NewStatus(app.conf, time.Now())
+		return err
+	})
+
+	switch {
+	case invalid != nil:
+		writeError(w, http.StatusBadRequest, invalid)
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err)
+	default:
+		writeJSON(w, http.StatusOK, status)
+	}
+}
+
+func allowMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+
+	w.Header().Set("Allow", method)
+	writeError(w, http.StatusMethodNotAllowed, errors.Errorf("method %s not allowed", r.Method))
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
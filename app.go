@@ -2,7 +2,12 @@ package main
 
 import (
 	"image/color"
+	"io"
+	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/faiface/pixel"
@@ -14,44 +19,128 @@ import (
 
 type App struct {
 	*cobra.Command
-	logger *zap.Logger
-	conf   Config
-	win    *pixelgl.Window
-	render *Render
-
-	initErr  error
-	shutdown bool
-	limitFPS bool
+	logger  *zap.Logger
+	conf    Config
+	journal *Journal
+	win     *pixelgl.Window
+	render  *Render
+
+	breaks    chan BreakEvent
+	detectors []io.Closer
+	requests  chan request
+	signals   chan os.Signal
+
+	notifier *Notifier
+	hooks    sync.WaitGroup
+
+	lock       *os.File // held by the widget as long as it is running
+	initErr    error
+	shutdown   bool
+	hidden     bool
+	afterHours bool // the day ended at the day boundary (see OvernightNewDay and OvernightAfterHours)
+	limitFPS   bool
 }
 
 func NewApp() *App {
-	app := &App{Command: &cobra.Command{
-		Use: "go-home",
-	}}
+	app := &App{
+		Command: &cobra.Command{
+			Use: "go-home",
+		},
+		breaks:   make(chan BreakEvent, 10),
+		requests: make(chan request),
+		signals:  make(chan os.Signal, 1),
+	}
 
 	app.SilenceUsage = true  // do not output usage in case of an error
 	app.SilenceErrors = true // we log them manually in the main function
 	app.Command.RunE = app.Run
 
 	var (
-		debug  bool
-		config string
+		debug   bool
+		config  string
+		state   string
+		journal string
 	)
 
 	flags := app.PersistentFlags()
-	flags.StringVar(&config, "config", os.ExpandEnv("$HOME/.go-home.yml"), "config file")
+	flags.StringVar(&config, "config", defaultConfigPath(), "config file")
+	flags.StringVar(&state, "state", defaultStatePath(), "file in which the state of the current day is stored")
+	flags.StringVar(&journal, "journal", defaultJournalPath(), "file to which the history of all work days is appended")
 	flags.BoolVar(&debug, "debug", false, "enable debug mode")
+	addOverrideFlags(app.Command)
 
-	cobra.OnInitialize(app.loadConfig(&debug, &config))
+	app.PersistentPreRun = func(cmd *cobra.Command, _ []string) {
+		app.logger = newLogger(debug)
+		if cmd == app.Command {
+			// The widget must hold the lock before loading the configuration
+			// since that may migrate files which another widget is using.
+			app.lock, app.initErr = lockInstance()
+			if app.initErr != nil {
+				return
+			}
+
+			// A settings file which was truncated by a crash is restored
+			// when the widget starts. The other commands report the error
+			// so config validate and config edit work on the actual file.
+			app.initErr = recoverCorruptFile(app.logger, config)
+			if app.initErr != nil {
+				return
+			}
+		}
+
+		app.initErr = app.loadConfig(debug, config, state, journal)
+	}
+
+	app.AddCommand(
+		app.reportCommand(),
+		app.exportCommand(),
+		app.importCommand(),
+		app.statusCommand(),
+		app.checkInCommand(),
+		app.checkOutCommand(),
+		app.breakCommand(),
+		app.controlCommand(),
+		app.configCommand(),
+	)
 
 	return app
 }
 
+// Execute runs the command line application and waits until all hooks have
+// finished.
+func (app *App) Execute() error {
+	err := app.Command.Execute()
+	app.hooks.Wait()
+	return err
+}
+
 func (app *App) Run(_ *cobra.Command, _ []string) error {
+	if app.initErr == errAlreadyRunning {
+		app.logger.Info("Go Home is already running")
+		return sendCommand("show")
+	}
+	if app.lock != nil {
+		defer app.lock.Close()
+	}
 	if app.initErr != nil {
 		return app.initErr
 	}
 
+	err := app.commitDay()
+	if err != nil {
+		return err
+	}
+
+	// All OpenGL calls must happen on the main thread. We only initialize the
+	// window here so the other sub commands also work without a GL context.
+	pixelgl.Run(func() {
+		err = app.run()
+	})
+
+	return err
+}
+
+func (app *App) run() error {
 	var err error
 	app.logger.Info("Starting application", zap.Object("config", app.conf))
 	err = app.createWindow(app.conf.UI)
@@ -64,11 +153,76 @@ func (app *App) Run(_ *cobra.Command, _ []string) error {
 		return errors.Wrap(err, "failed to create renderer")
 	}
 
+	if app.conf.DetectBreaks {
+		app.detectScreenLocks()
+		if app.conf.IdleThreshold > 0 {
+			app.detectIdleTime(app.conf.IdleThreshold)
+		}
+	}
+
+	if app.conf.Notifications.Enabled {
+		app.enableNotifications()
+	}
+
+	l, err := app.listen()
+	if err != nil {
+		app.logger.Warn("Other commands will not be able to control the widget", zap.Error(err))
+	}
+
+	w, err := app.watchConfig()
+	if err != nil {
+		app.logger.Warn("Changes to the configuration file require a restart", zap.Error(err))
+	}
+
+	var srv *http.Server
+	if app.conf.API.Listen != "" {
+		srv, err = app.serveAPI()
+		if err != nil {
+			app.logger.Error("Failed to start HTTP API", zap.Error(err))
+		}
+	}
+
+	// The session usually ends by terminating the widget, so it must still
+	// record the day then.
+	signal.Notify(app.signals, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP)
 	app.runLoop()
+	signal.Stop(app.signals)
+
+	if srv != nil {
+		srv.Close()
+	}
+
+	if w != nil {
+		w.Close()
+	}
+	if l != nil {
+		l.Close()
+	}
+
+	if app.notifier != nil {
+		app.notifier.Close()
+	}
+
+	for _, d := range app.detectors {
+		if err := d.Close(); err != nil {
+			app.logger.Warn("Failed to stop break detection", zap.Error(err))
+		}
+	}
+
+	err = app.record(app.conf.Exit(time.Now()))
+	if err != nil {
+		app.logger.Error("Failed to update journal on shutdown", zap.Error(err))
+	} else {
+		app.conf.LastSeen = time.Time{} // the exit is recorded (see finishDay)
+	}
 
-	err = app.conf.Save()
+	err = app.conf.SaveState()
 	if err != nil {
-		app.logger.Error("Failed to save configuration on shutdown", zap.Error(err))
+		app.logger.Error("Failed to save state on shutdown", zap.Error(err))
+	}
+
+	if !app.afterHours { // otherwise the day has already ended
+		app.runDayEndHooks(time.Now())
 	}
 
 	return nil
@@ -97,7 +251,7 @@ func (app *App) createWindow(conf UIConfig) error {
 	}
 
 	app.win.SetSmooth(true)
-	app.win.SetPos(app.conf.UI.WindowPos)
+	app.win.SetPos(app.conf.WindowPos)
 	app.win.Update()
 
 	return nil
@@ -105,25 +259,68 @@ func (app *App) createWindow(conf UIConfig) error {
 
 func (app *App) runLoop() {
 	app.limitFPS = true // might be disabled when moving the window via Shift+Arrow
-	fps := time.Tick(time.Second / time.Duration(app.conf.UI.FPS))
+	fps := time.NewTicker(time.Second / time.Duration(app.conf.UI.FPS))
+	defer func() { fps.Stop() }()
+	currentFPS := app.conf.UI.FPS
 	last := time.Now()
 	for !app.win.Closed() {
-		dt := time.Since(last).Seconds()
-		last = time.Now()
+		now := time.Now()
+		dt := now.Sub(last).Seconds()
+		since := last
+		last = now
 
-		// TODO: deal with a new dawn
-
-		app.win.Clear(color.White)
-		app.handleInput(app.win, dt)
-		app.render.Draw(app.win)
+		app.handleSignals()
+		app.handleNewDawn(now)
+		app.updateLastSeen(now)
+		app.handleRequests()
+		app.handleBreakEvents()
+		app.handleNotifications(since, now)
+		app.handleTimedHooks(since, now)
+		if !app.hidden {
+			if app.win.JustPressed(pixelgl.MouseButtonLeft) {
+				app.wake(now)
+			}
+			app.win.Clear(color.White)
+			app.handleInput(app.win, dt)
+			app.render.Draw(app.win)
+		}
 		app.win.Update()
 
 		if app.shutdown {
 			return
 		}
 
+		if app.conf.UI.FPS != currentFPS { // configuration was reloaded
+			fps.Stop()
+			fps = time.NewTicker(time.Second / time.Duration(app.conf.UI.FPS))
+			currentFPS = app.conf.UI.FPS
+		}
+
 		if app.limitFPS {
-			<-fps
+			<-fps.C
 		}
 	}
 }
+
+// handleSignals shuts down the widget like closing its window once it was
+// asked to terminate.
+func (app *App) handleSignals() {
+	select {
+	case sig := <-app.signals:
+		app.logger.Info("Shutting down", zap.Stringer("signal", sig))
+		app.shutdown = true
+	default:
+	}
+}
+
+// updateLastSeen saves the current time in the state file once a minute. If
+// the widget cannot shut down properly, e.g. because the X server is gone, the
+// day is recorded with this time as exit when the next day starts.
+func (app *App) updateLastSeen(now time.Time) {
+	if app.afterHours || now.Sub(app.conf.LastSeen) < time.Minute {
+		return
+	}
+
+	app.conf.LastSeen = now.Round(time.Second)
+	app.save()
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// writeFileAtomic replaces the file at path with data. The data is written to
+// a temporary file first which is then renamed, so the file is never left
+// half written if we crash or the disk is full. The previous version of the
+// file is kept as backup (see backupPath).
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return errors.Wrap(err, "failed to create directory")
+	}
+
+	tmp, err := ioutil.TempFile(dir, "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return errors.Wrap(err, "failed to create temporary file")
+	}
+	defer os.Remove(tmp.Name()) // fails silently after the rename
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), perm)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to write temporary file")
+	}
+
+	err = backup(path)
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tmp.Name(), path)
+	if err != nil {
+		return errors.Wrap(err, "failed to replace file")
+	}
+
+	// Make sure the rename itself is persisted. Not all file systems support
+	// syncing directories so errors are ignored.
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+
+	return nil
+}
+
+func backupPath(path string) string {
+	return path + ".bak"
+}
+
+// backup keeps the current version of the file at path. It is hard linked if
+// possible so the file itself is never missing.
+func backup(path string) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+
+	os.Remove(backupPath(path))
+	if os.Link(path, backupPath(path)) == nil {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return errors.Wrap(err, "failed to read file for backup")
+	}
+
+	return errors.Wrap(ioutil.WriteFile(backupPath(path), data, 0644), "failed to write backup")
+}
+
+// recoverCorruptFile restores the backup of the given YAML file if the file is
+// empty or cannot be parsed, e.g. because it was truncated. The corrupt file
+// is kept with a ".corrupt" suffix. Nothing happens if there is no valid
+// backup, in which case loading the file fails as usual.
+func recoverCorruptFile(logger *zap.Logger, path string) error {
+	err := checkYAMLFile(path)
+	if err == nil || os.IsNotExist(err) {
+		return nil
+	}
+
+	if checkYAMLFile(backupPath(path)) != nil {
+		return nil
+	}
+
+	logger.Warn("File is corrupt. Restoring backup",
+		zap.String("path", path),
+		zap.String("backup", backupPath(path)),
+		zap.Error(err),
+	)
+
+	data, err := ioutil.ReadFile(backupPath(path))
+	if err != nil {
+		return errors.Wrap(err, "failed to read backup")
+	}
+
+	err = os.Rename(path, path+".corrupt")
+	if err != nil {
+		return errors.Wrap(err, "failed to move corrupt file")
+	}
+
+	return writeFileAtomic(path, data, 0644)
+}
+
+// checkYAMLFile returns an error if the file is empty or not valid YAML.
+func checkYAMLFile(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		return errors.New("file is empty")
+	}
+
+	var v interface{}
+	return yaml.Unmarshal(data, &v)
+}
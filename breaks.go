@@ -0,0 +1,129 @@
+package main
+
+import (
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// A Break is a period of time during a work day which does not count as work.
+// An ongoing break has a zero End time.
+type Break struct {
+	Start  time.Time `yaml:"start"`
+	End    time.Time `yaml:"end,omitempty"`
+	Source string    `yaml:"source"`
+}
+
+// BreakEvent signals the start or the end of a break that was detected
+// automatically (e.g. because the screen was locked).
+type BreakEvent struct {
+	Source string
+	Start  bool // true if the break started and false if it ended
+	Time   time.Time
+}
+
+// HandleBreakEvent starts or ends a break of the events source. Repeated
+// events (e.g. two different signals for the same screen lock) are ignored.
+func (conf *Config) HandleBreakEvent(e BreakEvent) (changed bool) {
+	for i := len(conf.Breaks) - 1; i >= 0; i-- {
+		b := &conf.Breaks[i]
+		if b.Source != e.Source || !b.End.IsZero() {
+			continue
+		}
+
+		if e.Start {
+			return false // break has already started
+		}
+
+		b.End = e.Time.Round(time.Second)
+		return true
+	}
+
+	if !e.Start {
+		return false // break has never started
+	}
+
+	conf.Breaks = append(conf.Breaks, Break{
+		Start:  e.Time.Round(time.Second),
+		Source: e.Source,
+	})
+
+	return true
+}
+
+// BreakDuration returns how much time was spent in breaks until now.
+// Overlapping breaks (e.g. an idle period while the screen was locked) are
+// only counted once.
+func (conf Config) BreakDuration(now time.Time) time.Duration {
+	var total time.Duration
+	var last time.Time // end of the previously counted break
+	for _, b := range conf.breaksUntil(now) {
+		if b.Start.Before(last) {
+			b.Start = last
+		}
+		if b.End.After(b.Start) {
+			total += b.End.Sub(b.Start)
+			last = b.End
+		}
+	}
+
+	return total
+}
+
+// breaksUntil returns all breaks sorted by their start time. Ongoing breaks
+// and breaks which extend into the future are cut off at now.
+func (conf Config) breaksUntil(now time.Time) []Break {
+	breaks := make([]Break, 0, len(conf.Breaks))
+	for _, b := range conf.Breaks {
+		if b.Start.After(now) {
+			continue
+		}
+		if b.End.IsZero() || b.End.After(now) {
+			b.End = now
+		}
+		breaks = append(breaks, b)
+	}
+
+	sort.Slice(breaks, func(i, j int) bool {
+		return breaks[i].Start.Before(breaks[j].Start)
+	})
+
+	return breaks
+}
+
+// handleBreakEvents processes all pending break events without blocking and
+// moves the check-out time accordingly.
+func (app *App) handleBreakEvents() {
+	for {
+		select {
+		case e := <-app.breaks:
+			if !e.Start {
+				app.wake(e.Time) // the screen was unlocked after the day boundary
+			}
+			if app.afterHours || !app.conf.HandleBreakEvent(e) {
+				continue
+			}
+
+			app.logger.Info("Detected break",
+				zap.String("source", e.Source),
+				zap.Bool("start", e.Start),
+			)
+
+			event := HookBreakEnd
+			if e.Start {
+				event = HookBreakStart
+			}
+			app.runHooks(event, e.Time, "GO_HOME_BREAK_SOURCE="+e.Source)
+
+			// Save immediately so other commands (e.g. "go-home break add")
+			// do not work on an outdated file.
+			app.save()
+		default:
+			app.conf.UpdateCheckOut(time.Now())
+			app.render.CheckOut = app.conf.CheckOut
+			app.render.Breaks = app.conf.Breaks
+			return
+		}
+	}
+}
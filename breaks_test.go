@@ -0,0 +1,167 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// clock returns the given time of day on a fixed date for tests.
+func clock(t *testing.T, s string) time.Time {
+	c, err := time.Parse("15:04:05"[:len(s)], s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return time.Date(2019, 6, 17, c.Hour(), c.Minute(), c.Second(), 0, time.Local)
+}
+
+func TestHandleBreakEvent(t *testing.T) {
+	lock := func(s string) BreakEvent { return BreakEvent{Source: "screen_lock", Start: true, Time: clock(t, s)} }
+	unlock := func(s string) BreakEvent { return BreakEvent{Source: "screen_lock", Time: clock(t, s)} }
+	idle := func(s string) BreakEvent { return BreakEvent{Source: "idle", Start: true, Time: clock(t, s)} }
+	active := func(s string) BreakEvent { return BreakEvent{Source: "idle", Time: clock(t, s)} }
+
+	tests := []struct {
+		name    string
+		events  []BreakEvent
+		changed []bool
+		breaks  []Break
+	}{
+		{
+			name:    "lock and unlock",
+			events:  []BreakEvent{lock("12:00"), unlock("12:30")},
+			changed: []bool{true, true},
+			breaks:  []Break{{Start: clock(t, "12:00"), End: clock(t, "12:30"), Source: "screen_lock"}},
+		},
+		{
+			name:    "ongoing break",
+			events:  []BreakEvent{lock("12:00")},
+			changed: []bool{true},
+			breaks:  []Break{{Start: clock(t, "12:00"), Source: "screen_lock"}},
+		},
+		{
+			name:    "repeated lock signals",
+			events:  []BreakEvent{lock("12:00"), lock("12:00:01"), unlock("12:30"), unlock("12:30:01")},
+			changed: []bool{true, false, true, false},
+			breaks:  []Break{{Start: clock(t, "12:00"), End: clock(t, "12:30"), Source: "screen_lock"}},
+		},
+		{
+			name:    "unlock without lock",
+			events:  []BreakEvent{unlock("09:00")},
+			changed: []bool{false},
+		},
+		{
+			name:    "multiple breaks",
+			events:  []BreakEvent{lock("10:00"), unlock("10:15"), lock("12:00"), unlock("12:45")},
+			changed: []bool{true, true, true, true},
+			breaks: []Break{
+				{Start: clock(t, "10:00"), End: clock(t, "10:15"), Source: "screen_lock"},
+				{Start: clock(t, "12:00"), End: clock(t, "12:45"), Source: "screen_lock"},
+			},
+		},
+		{
+			name:    "sources are paired independently",
+			events:  []BreakEvent{idle("11:50"), lock("12:00"), active("12:20"), unlock("12:30")},
+			changed: []bool{true, true, true, true},
+			breaks: []Break{
+				{Start: clock(t, "11:50"), End: clock(t, "12:20"), Source: "idle"},
+				{Start: clock(t, "12:00"), End: clock(t, "12:30"), Source: "screen_lock"},
+			},
+		},
+		{
+			name:    "times are rounded to seconds",
+			events:  []BreakEvent{lock("12:00"), {Source: "screen_lock", Time: clock(t, "12:30").Add(600 * time.Millisecond)}},
+			changed: []bool{true, true},
+			breaks:  []Break{{Start: clock(t, "12:00"), End: clock(t, "12:30:01"), Source: "screen_lock"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var conf Config
+			for i, e := range tt.events {
+				if changed := conf.HandleBreakEvent(e); changed != tt.changed[i] {
+					t.Errorf("event %d: HandleBreakEvent() = %v, want %v", i, changed, tt.changed[i])
+				}
+			}
+
+			if !reflect.DeepEqual(conf.Breaks, tt.breaks) {
+				t.Errorf("breaks = %+v, want %+v", conf.Breaks, tt.breaks)
+			}
+		})
+	}
+}
+
+func TestBreakDuration(t *testing.T) {
+	tests := []struct {
+		name   string
+		breaks []Break
+		now    string
+		want   time.Duration
+	}{
+		{
+			name: "no breaks",
+			now:  "17:00",
+		},
+		{
+			name:   "finished break",
+			breaks: []Break{{Start: clock(t, "12:00"), End: clock(t, "12:30")}},
+			now:    "17:00",
+			want:   30 * time.Minute,
+		},
+		{
+			name:   "open break counts until now",
+			breaks: []Break{{Start: clock(t, "12:00")}},
+			now:    "12:20",
+			want:   20 * time.Minute,
+		},
+		{
+			name:   "break in the future",
+			breaks: []Break{{Start: clock(t, "12:00"), End: clock(t, "12:30")}},
+			now:    "11:00",
+		},
+		{
+			name:   "break which has not ended yet",
+			breaks: []Break{{Start: clock(t, "12:00"), End: clock(t, "12:30")}},
+			now:    "12:10",
+			want:   10 * time.Minute,
+		},
+		{
+			name: "overlapping breaks are counted once",
+			breaks: []Break{
+				{Start: clock(t, "12:00"), End: clock(t, "12:30"), Source: "screen_lock"},
+				{Start: clock(t, "11:50"), End: clock(t, "12:20"), Source: "idle"},
+			},
+			now:  "17:00",
+			want: 40 * time.Minute,
+		},
+		{
+			name: "break inside another break",
+			breaks: []Break{
+				{Start: clock(t, "12:00"), End: clock(t, "13:00")},
+				{Start: clock(t, "12:10"), End: clock(t, "12:20")},
+			},
+			now:  "17:00",
+			want: time.Hour,
+		},
+		{
+			name: "open break overlapping a finished one",
+			breaks: []Break{
+				{Start: clock(t, "10:00"), End: clock(t, "10:15")},
+				{Start: clock(t, "10:10")},
+			},
+			now:  "10:30",
+			want: 30 * time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := Config{State: State{Breaks: tt.breaks}}
+			if got := conf.BreakDuration(clock(t, tt.now)); got != tt.want {
+				t.Errorf("BreakDuration() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
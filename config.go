@@ -3,9 +3,9 @@ package main
 import (
 	"fmt"
 	"io"
-	"io/ioutil"
-	"math"
 	"os"
+	"path/filepath"
+	"reflect"
 	"strconv"
 	"strings"
 	"time"
@@ -17,70 +17,242 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
+// The available modes of Config.Overnight.
+const (
+	OvernightNewDay      = "new_day"      // finish the day at the day boundary and start a new one on the next activity
+	OvernightAfterHours  = "after_hours"  // finish the day at the day boundary and stop tracking
+	OvernightPreviousDay = "previous_day" // count all work until the restart towards the previous day
+)
+
+// Config contains the settings of the user and the State of the current day.
+// Both are stored in separate files.
 type Config struct {
-	CheckIn  time.Time `yaml:"check_in"`
+	Version  int `yaml:"version"` // see configVersion
+	State    `yaml:"-"`
 	CheckOut time.Time `yaml:"-"`
 	EndOfDay time.Time `yaml:"-"`
 
 	WorkDuration  time.Duration `yaml:"work_duration"`
 	LunchDuration time.Duration `yaml:"lunch_duration"`
 	DayEnd        ClockTime     `yaml:"day_end"`
+	DayBoundary   ClockTime     `yaml:"day_boundary"` // when a work day ends and the next one starts
+	Schedule      Schedule      `yaml:"schedule,omitempty"`
+	Holidays      HolidayConfig `yaml:"holidays,omitempty"`
+	Overnight     string        `yaml:"overnight"`      // what to do when working past the day boundary
+	Today         WorkDay       `yaml:"-"`              // effective settings of the current day
+	DetectBreaks  bool          `yaml:"detect_breaks"`  // use screen locks instead of LunchDuration
+	IdleThreshold time.Duration `yaml:"idle_threshold"` // count idle periods as breaks (requires DetectBreaks)
+
+	Flexitime FlexitimeConfig `yaml:"flexitime"`
+	Balance   time.Duration   `yaml:"-"` // flexitime balance of all previous days
+
+	Notifications NotificationConfig `yaml:"notifications,omitempty"`
+	Hooks         HookConfig         `yaml:"hooks,omitempty"`
+	API           APIConfig          `yaml:"api,omitempty"`
 
 	UI    UIConfig `yaml:"ui"`
 	Debug bool     `yaml:"-"`
 
-	path string `yaml:"-"`
+	path      string           `yaml:"-"`
+	statePath string           `yaml:"-"`
+	calendar  Calendar         `yaml:"-"`
+	newDay    bool             `yaml:"-"` // set if loading the file has started a new day
+	lastDay   *Day             `yaml:"-"` // the day which was replaced by the new one, if it must be recorded
+	unsaved   bool             `yaml:"-"` // set if the settings file does not exist or is of an older version
+	warnings  []configProblem  `yaml:"-"` // problems which do not prevent loading the file
+	overrides []configOverride `yaml:"-"` // settings given as flags or environment variables
+}
+
+// FlexitimeConfig controls how over- and undertime is carried across days.
+type FlexitimeConfig struct {
+	Enabled        bool          `yaml:"enabled"`
+	InitialBalance time.Duration `yaml:"initial_balance"`  // balance before the first day in the journal
+	AdjustCheckOut bool          `yaml:"adjust_check_out"` // subtract the balance from today's check-out
 }
 
 type UIConfig struct {
-	FPS               int       `yaml:"fps"`
-	WindowWidth       int       `yaml:"width"`
-	WindowHeight      int       `yaml:"height"`
-	WindowPos         pixel.Vec `yaml:"pos"`
-	ShowRemainingTime bool      `yaml:"show_remaining_time"`
-}
-
-func (app *App) loadConfig(debug *bool, path *string) func() {
-	return func() {
-		app.logger = newLogger(*debug)
-		if app.initErr != nil {
-			return
-		}
+	FPS               int  `yaml:"fps"`
+	WindowWidth       int  `yaml:"width"`
+	WindowHeight      int  `yaml:"height"`
+	ShowRemainingTime bool `yaml:"show_remaining_time"`
+}
+
+func (app *App) loadConfig(debug bool, path, statePath, journalPath string) error {
+	app.logger.Debug("Running in debug mode")
 
-		app.logger.Debug("Running in debug mode")
-
-		var r io.Reader
-		if f, err := os.Open(*path); err == nil {
-			app.logger.Info("Loading configuration", zap.String("path", *path))
-			r = f
-			defer f.Close()
-		} else if os.IsNotExist(err) {
-			app.logger.Info("Configuration file not found. Creating new file", zap.String("path", *path))
-		} else {
-			app.initErr = errors.Wrap(err, "failed to open config file")
-			return
+	err := app.migrate(path, statePath, journalPath)
+	if err != nil {
+		return err
+	}
+
+	app.conf, err = app.readConfig(path, statePath, debug)
+	if err != nil {
+		return err
+	}
+
+	app.journal = NewJournal(journalPath)
+	if app.conf.Flexitime.Enabled {
+		return app.loadBalance(&app.conf)
+	}
+
+	return nil
+}
+
+// commitDay persists what loading the configuration has computed: the settings
+// file if it did not exist or was migrated, the state, the previous day if a
+// new one was started over it and the current day in the journal. If loading
+// has started a new day, the day_start hooks are run.
+// Loading alone never writes any file, so read-only commands like status do
+// not check in. Only the widget and the correction commands call this.
+func (app *App) commitDay() error {
+	// The settings file is already restored before loading when the widget
+	// starts (see NewApp).
+	err := recoverCorruptFile(app.logger, app.conf.statePath)
+	if err != nil {
+		return err
+	}
+
+	// The previous day is recorded before the state is overwritten so it
+	// cannot get lost if the journal cannot be written.
+	if app.conf.lastDay != nil {
+		err = app.journal.Append(*app.conf.lastDay)
+		if err != nil {
+			return err
 		}
+		app.conf.lastDay = nil
+	}
+
+	if app.conf.unsaved {
+		err = app.conf.Save()
+		app.conf.unsaved = false
+	} else {
+		err = app.conf.SaveState()
+	}
+	if err != nil {
+		return err
+	}
+
+	err = app.record(time.Time{})
+	if err != nil {
+		return err
+	}
+
+	if app.conf.newDay {
+		app.conf.newDay = false
+		app.runHooks(HookDayStart, time.Now())
+	}
 
-		app.conf, app.initErr = LoadConfig(r, app.logger, *path, *debug)
-		if app.initErr != nil {
-			return
+	return nil
+}
+
+// migrate moves the files of older versions to their current location. This
+// only happens if the default locations are used.
+func (app *App) migrate(path, statePath, journalPath string) error {
+	flags := app.PersistentFlags()
+	if !flags.Changed("config") && !flags.Changed("state") {
+		err := migrateLegacyConfig(app.logger, legacyConfigPath(), path, statePath)
+		if err != nil {
+			return errors.Wrap(err, "failed to migrate configuration")
 		}
+	}
 
-		app.initErr = app.conf.Save()
+	if !flags.Changed("journal") {
+		return migrateLegacyJournal(app.logger, legacyJournalPath(), journalPath)
 	}
+
+	return nil
 }
 
-func LoadConfig(r io.Reader, logger *zap.Logger, path string, debug bool) (Config, error) {
-	conf := Config{path: path}
-	if r != nil {
-		dec := yaml.NewDecoder(r)
-		dec.KnownFields(true)
-		err := dec.Decode(&conf)
+// readConfig loads the settings and the state file at the given paths. If a
+// file does not exist yet, the defaults are used instead.
+func (app *App) readConfig(path, statePath string, debug bool) (Config, error) {
+	settings, err := app.openConfigFile(path, "configuration")
+	if err != nil {
+		return Config{}, err
+	}
+	if settings != nil {
+		defer settings.Close()
+	}
+
+	// A corrupt state file is restored by commitDay. Until then we read
+	// the backup so loading does not change any file.
+	readStatePath := statePath
+	if err := checkYAMLFile(statePath); err != nil && !os.IsNotExist(err) && checkYAMLFile(backupPath(statePath)) == nil {
+		app.logger.Warn("State file is corrupt. Reading backup instead", zap.String("path", statePath), zap.Error(err))
+		readStatePath = backupPath(statePath)
+	}
+
+	state, err := app.openConfigFile(readStatePath, "state")
+	if err != nil {
+		return Config{}, err
+	}
+	if state != nil {
+		defer state.Close()
+	}
+
+	return LoadConfig(settings, state, app.overrides(), app.logger, path, statePath, debug)
+}
+
+// openConfigFile opens the given file for reading. It returns a nil reader if
+// the file does not exist.
+func (app *App) openConfigFile(path, name string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		app.logger.Info("No "+name+" file found. Using defaults", zap.String("path", path))
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open %s file", name)
+	}
+
+	app.logger.Info("Loading "+name, zap.String("path", path))
+	return f, nil
+}
+
+// loadBalance computes the flexitime balance from the journal and updates the
+// check-out time accordingly.
+func (app *App) loadBalance(conf *Config) error {
+	balance, incomplete, err := app.journal.Balance(conf.WorkDate(conf.CheckIn).Format("2006-01-02"), conf.calendar)
+	if err != nil {
+		return errors.Wrap(err, "failed to compute flexitime balance")
+	}
+	if len(incomplete) > 0 {
+		app.logger.Warn("Days without exit time do not count towards the flexitime balance",
+			zap.Strings("dates", incomplete),
+		)
+	}
+
+	conf.Balance = conf.Flexitime.InitialBalance + balance
+	conf.UpdateCheckOut(time.Now())
+	return nil
+}
+
+// LoadConfig decodes the settings and the state. Both readers may be nil to
+// use the defaults. The overrides take precedence over the settings file. All
+// problems of the settings are returned together as configError.
+func LoadConfig(settings, state io.Reader, overrides []configOverride, logger *zap.Logger, path, statePath string, debug bool) (Config, error) {
+	conf := Config{Version: configVersion, path: path, statePath: statePath, unsaved: settings == nil}
+	checker := new(configChecker)
+	if settings != nil {
+		err := conf.decodeSettings(settings, checker, logger)
 		if err != nil {
-			return conf, errors.Wrap(err, "failed to decode config")
+			return conf, err
+		}
+	}
+	if state != nil {
+		// The state file is only written by Go Home itself, so unknown
+		// fields can only come from a newer version and are ignored.
+		err := yaml.NewDecoder(state).Decode(&conf.State)
+		if err != nil && err != io.EOF {
+			return conf, errors.Wrap(err, "failed to decode state")
 		}
 	}
 
+	err := conf.applyOverrides(overrides, checker)
+	if err != nil {
+		return conf, err
+	}
+
 	if conf.UI.WindowWidth == 0 {
 		conf.UI.WindowWidth = 512
 	}
@@ -88,9 +260,9 @@ func LoadConfig(r io.Reader, logger *zap.Logger, path string, debug bool) (Confi
 		conf.UI.WindowHeight = 32
 	}
 
-	if conf.UI.WindowPos.X == 0 && conf.UI.WindowPos.Y == 0 {
+	if conf.WindowPos.X == 0 && conf.WindowPos.Y == 0 {
 		var displayWidth float64 = 1920 // TODO: make dynamic
-		conf.UI.WindowPos = pixel.Vec{
+		conf.WindowPos = pixel.Vec{
 			X: displayWidth/2 - float64(conf.UI.WindowWidth)/2,
 			Y: 35,
 		}
@@ -102,46 +274,216 @@ func LoadConfig(r io.Reader, logger *zap.Logger, path string, debug bool) (Confi
 	if conf.LunchDuration == 0 {
 		conf.LunchDuration = 1 * time.Hour
 	}
-	if conf.DayEnd.Hour == 0 {
+	if conf.DayEnd == (ClockTime{}) {
 		conf.DayEnd = ClockTime{Hour: 20, Minute: 00}
 	}
 	if conf.UI.FPS == 0 {
 		conf.UI.FPS = 10
 	}
-	if conf.CheckIn.IsZero() || isDifferentDay(conf.CheckIn, time.Now()) {
-		conf.CheckIn = time.Now()
-		logger.Info("Detected start of new day", zap.String("date", conf.CheckIn.Format("2006-01-02")))
+	if conf.Overnight == "" {
+		conf.Overnight = OvernightNewDay
+	}
+
+	conf.check(checker)
+	err = checker.err()
+	if err != nil {
+		return conf, err
+	}
+
+	conf.calendar, err = LoadCalendar(conf.Holidays, filepath.Dir(path))
+	if err != nil {
+		return conf, err
+	}
+
+	if conf.CheckIn.IsZero() || (conf.isDifferentDay(conf.CheckIn, time.Now()) && !conf.continuesDay(time.Now())) {
+		conf.finishDay()
+		conf.StartDay(time.Now())
+		conf.newDay = true
+		logger.Info("Detected start of new day", zap.String("date", conf.WorkDate(conf.CheckIn).Format("2006-01-02")))
 	}
 
 	conf.CheckIn = conf.CheckIn.Round(time.Second)
-	conf.CheckOut = conf.CheckIn.Add(conf.WorkDuration).Add(conf.LunchDuration)
-	conf.EndOfDay = conf.DayEnd.Time(conf.CheckIn)
+	conf.updateDay(time.Now())
 	conf.Debug = debug
 
+	warnings := &configChecker{root: checker.root}
+	conf.checkIneffective(warnings)
+	conf.checkDay(warnings)
+	conf.warnings = append(conf.warnings, warnings.problems...)
+	for _, p := range warnings.problems {
+		logger.Warn("Questionable configuration", zap.String("problem", p.String()))
+	}
+
 	return conf, nil
 }
 
-func isDifferentDay(a, b time.Time) bool {
-	yearA, monthA, dayA := a.Date()
-	yearB, monthB, dayB := b.Date()
+// decodeSettings decodes the settings file into conf. Files of older versions
+// are migrated first. Unknown settings and values which cannot be decoded are
+// reported to the checker so all problems can be shown at once.
+func (conf *Config) decodeSettings(r io.Reader, checker *configChecker, logger *zap.Logger) error {
+	var doc yaml.Node
+	err := yaml.NewDecoder(r).Decode(&doc)
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to decode config")
+	}
+
+	if len(doc.Content) == 0 {
+		return nil
+	}
+
+	root := doc.Content[0]
+	version, err := migrateConfig(root)
+	if err != nil {
+		return errors.Wrap(err, "failed to migrate config")
+	}
+
+	// Files of newer versions may contain settings we do not know yet.
+	strict := version <= configVersion
+	if !strict {
+		logger.Warn("Configuration was written by a newer version of Go Home. Unknown settings are ignored",
+			zap.Int("version", version),
+			zap.Int("supported_version", configVersion),
+		)
+		key, _ := findYAMLPath(root, "version")
+		conf.warnings = append(conf.warnings, configProblem{
+			Line:    key.Line,
+			Path:    "version",
+			Message: fmt.Sprintf("file was written by a newer version of Go Home (supported version is %d), unknown settings are not checked", configVersion),
+		})
+	}
+
+	checker.root = root
+	checker.checkNode("", root, reflect.TypeOf(*conf), strict)
+	err = root.Decode(conf)
+	if err != nil {
+		return errors.Wrap(err, "failed to decode config")
+	}
+
+	if version < configVersion {
+		logger.Info("Migrating configuration", zap.Int("from", version), zap.Int("to", configVersion))
+		conf.Version = configVersion
+		conf.unsaved = true
+	}
+
+	return nil
+}
+
+// StartDay resets the state of the previous day and checks in at the given
+// time.
+func (conf *Config) StartDay(checkIn time.Time) {
+	conf.CheckIn = checkIn.Round(time.Second)
+	conf.Breaks = nil
+	conf.CheckedOut = time.Time{}
+	conf.LastSeen = time.Time{}
+	conf.updateDay(checkIn)
+}
+
+// updateDay computes all values which depend on the check-in time.
+func (conf *Config) updateDay(now time.Time) {
+	conf.Today = conf.workDay(conf.WorkDate(conf.CheckIn))
+	conf.UpdateCheckOut(now)
+	conf.EndOfDay = conf.At(conf.Today.DayEnd)
+	if conf.Today.DayEnd.minutes() == conf.DayBoundary.minutes() {
+		conf.EndOfDay = conf.EndOfWorkDay() // not the start of the work day
+	}
+}
+
+// UpdateCheckOut computes when it is time to go home. If configured, the
+// flexitime balance is used to shift the regular check-out time.
+func (conf *Config) UpdateCheckOut(now time.Time) {
+	conf.CheckOut = conf.regularCheckOut(now)
+	if conf.Flexitime.Enabled && conf.Flexitime.AdjustCheckOut {
+		conf.CheckOut = conf.CheckOut.Add(-conf.Balance)
+	}
+}
+
+// regularCheckOut returns the check-out time without taking the flexitime
+// balance into account. Overtime is always measured against this time.
+func (conf Config) regularCheckOut(now time.Time) time.Time {
+	return conf.CheckIn.Add(conf.Today.WorkDuration).Add(conf.BreakTime(now))
+}
+
+// BreakTime returns how much time of the day does not count as work. If break
+// detection is enabled, these are the actual breaks until now. Otherwise the
+// lunch duration is the minimum and manual breaks only count once they take
+// longer than that in total.
+func (conf Config) BreakTime(now time.Time) time.Duration {
+	breaks := conf.BreakDuration(now)
+	if conf.DetectBreaks || breaks > conf.Today.LunchDuration {
+		return breaks
+	}
+
+	return conf.Today.LunchDuration
+}
+
+// Exit returns the time at which the work day ended. This is either the
+// manually set check-out time or now.
+func (conf Config) Exit(now time.Time) time.Time {
+	if conf.CheckedOut.IsZero() {
+		return now
+	}
+
+	return conf.CheckedOut
+}
+
+// WorkDate returns the date of the work day to which t belongs. A work day
+// starts at the DayBoundary, so with a boundary of 06:00 a night shift from
+// 22:00 until 05:00 belongs entirely to the day on which it started.
+func (conf Config) WorkDate(t time.Time) time.Time {
+	if t.Hour()*60+t.Minute() < conf.DayBoundary.minutes() {
+		t = t.AddDate(0, 0, -1)
+	}
+
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// TimeOn returns the time at which the clock time t occurs during the work
+// day starting on the given date. Clock times before the day boundary belong
+// to the following calendar day.
+func (conf Config) TimeOn(date time.Time, t ClockTime) time.Time {
+	if t.minutes() < conf.DayBoundary.minutes() {
+		date = date.AddDate(0, 0, 1)
+	}
+
+	return t.Time(date)
+}
+
+// At returns the time at which the clock time t occurs during the current
+// work day.
+func (conf Config) At(t ClockTime) time.Time {
+	return conf.TimeOn(conf.WorkDate(conf.CheckIn), t)
+}
+
+// EndOfWorkDay returns the day boundary at which the current work day ends.
+func (conf Config) EndOfWorkDay() time.Time {
+	next := conf.WorkDate(conf.CheckIn).AddDate(0, 0, 1)
+	return conf.DayBoundary.Time(next)
+}
+
+func (conf Config) isDifferentDay(a, b time.Time) bool {
+	yearA, monthA, dayA := conf.WorkDate(a).Date()
+	yearB, monthB, dayB := conf.WorkDate(b).Date()
 	return yearA != yearB || monthA != monthB || dayA != dayB
 }
 
+// Save writes the settings and the state file. Overridden settings keep the
+// value of the file.
 func (conf Config) Save() error {
-	conf.UI.WindowPos.X = math.Round(conf.UI.WindowPos.X)
-	conf.UI.WindowPos.Y = math.Round(conf.UI.WindowPos.Y)
-
-	data, err := yaml.Marshal(conf)
+	data, err := marshalYAMLInto(conf.path, conf, migrateSettings, conf.overriddenPaths()...)
 	if err != nil {
 		return errors.Wrap(err, "failed to encode config as YAML")
 	}
 
-	err = ioutil.WriteFile(conf.path, data, 0666)
+	err = writeFileAtomic(conf.path, data, 0644)
 	if err != nil {
 		return errors.Wrap(err, "failed to save config")
 	}
 
-	return nil
+	return conf.SaveState()
 }
 
 func (conf Config) MarshalLogObject(enc zapcore.ObjectEncoder) error {
@@ -150,10 +492,26 @@ func (conf Config) MarshalLogObject(enc zapcore.ObjectEncoder) error {
 	} else {
 		enc.AddString("check_in", conf.CheckIn.Format("2006-01-02 15:04"))
 	}
+	if !conf.CheckedOut.IsZero() {
+		enc.AddString("checked_out", conf.CheckedOut.Format("2006-01-02 15:04"))
+	}
 
-	enc.AddDuration("work_duration", conf.WorkDuration)
-	enc.AddDuration("lunch_duration", conf.LunchDuration)
-	enc.AddString("day_end", conf.DayEnd.String())
+	enc.AddDuration("work_duration", conf.Today.WorkDuration)
+	enc.AddDuration("lunch_duration", conf.Today.LunchDuration)
+	enc.AddString("day_end", conf.Today.DayEnd.String())
+	if conf.DayBoundary != (ClockTime{}) {
+		enc.AddString("day_boundary", conf.DayBoundary.String())
+	}
+	if conf.Today.Holiday != "" {
+		enc.AddString("holiday", conf.Today.Holiday)
+	} else if conf.Today.Off {
+		enc.AddBool("day_off", true)
+	}
+	enc.AddBool("detect_breaks", conf.DetectBreaks)
+	enc.AddDuration("idle_threshold", conf.IdleThreshold)
+	if conf.Flexitime.Enabled {
+		enc.AddDuration("balance", conf.Balance)
+	}
 
 	return nil
 }
@@ -195,6 +553,10 @@ func (t ClockTime) Time(ref time.Time) time.Time {
 	return time.Date(year, month, day, t.Hour, t.Minute, 0, 0, ref.Location())
 }
 
+func (t ClockTime) minutes() int {
+	return t.Hour*60 + t.Minute
+}
+
 func (t ClockTime) String() string {
 	return fmt.Sprintf("%02d:%02d", t.Hour, t.Minute)
 }
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWorkDate(t *testing.T) {
+	tests := []struct {
+		name     string
+		boundary ClockTime
+		at       string
+		want     string
+	}{
+		{name: "midnight boundary, morning", at: "08:00", want: "2019-06-17"},
+		{name: "midnight boundary, start of day", at: "00:00", want: "2019-06-17"},
+		{name: "midnight boundary, end of day", at: "23:59:59", want: "2019-06-17"},
+		{name: "before the boundary", boundary: ClockTime{Hour: 6}, at: "05:59", want: "2019-06-16"},
+		{name: "at the boundary", boundary: ClockTime{Hour: 6}, at: "06:00", want: "2019-06-17"},
+		{name: "night shift", boundary: ClockTime{Hour: 6}, at: "22:00", want: "2019-06-17"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := Config{DayBoundary: tt.boundary}
+			got := conf.WorkDate(clock(t, tt.at))
+			if got.Format("2006-01-02") != tt.want || got.Hour() != 0 || got.Minute() != 0 {
+				t.Errorf("WorkDate() = %s, want %s 00:00", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimeOn(t *testing.T) {
+	nextDay := func(s string) time.Time { return clock(t, s).AddDate(0, 0, 1) }
+
+	tests := []struct {
+		name     string
+		boundary ClockTime
+		t        ClockTime
+		want     time.Time
+	}{
+		{name: "midnight boundary", t: ClockTime{Hour: 8, Minute: 30}, want: clock(t, "08:30")},
+		{name: "midnight boundary, end of day", t: ClockTime{Hour: 24}, want: nextDay("00:00")},
+		{name: "after the boundary", boundary: ClockTime{Hour: 6}, t: ClockTime{Hour: 22}, want: clock(t, "22:00")},
+		{name: "before the boundary", boundary: ClockTime{Hour: 6}, t: ClockTime{Hour: 5, Minute: 59}, want: nextDay("05:59")},
+		{name: "at the boundary", boundary: ClockTime{Hour: 6}, t: ClockTime{Hour: 6}, want: clock(t, "06:00")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := Config{DayBoundary: tt.boundary}
+			if got := conf.TimeOn(clock(t, "00:00"), tt.t); !got.Equal(tt.want) {
+				t.Errorf("TimeOn() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsDifferentDay(t *testing.T) {
+	nextDay := func(s string) time.Time { return clock(t, s).AddDate(0, 0, 1) }
+
+	tests := []struct {
+		name     string
+		boundary ClockTime
+		a, b     time.Time
+		want     bool
+	}{
+		{name: "same day", a: clock(t, "08:00"), b: clock(t, "23:59"), want: false},
+		{name: "after midnight", a: clock(t, "08:00"), b: nextDay("00:00"), want: true},
+		{name: "night shift", boundary: ClockTime{Hour: 6}, a: clock(t, "22:00"), b: nextDay("05:59"), want: false},
+		{name: "night shift reaching the boundary", boundary: ClockTime{Hour: 6}, a: clock(t, "22:00"), b: nextDay("06:00"), want: true},
+		{name: "before and after the boundary", boundary: ClockTime{Hour: 6}, a: clock(t, "05:00"), b: clock(t, "07:00"), want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := Config{DayBoundary: tt.boundary}
+			if got := conf.isDifferentDay(tt.a, tt.b); got != tt.want {
+				t.Errorf("isDifferentDay() = %v, want %v", got, tt.want)
+			}
+			if got := conf.isDifferentDay(tt.b, tt.a); got != tt.want {
+				t.Errorf("isDifferentDay() with swapped arguments = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEndOfDay(t *testing.T) {
+	nextDay := func(s string) time.Time { return clock(t, s).AddDate(0, 0, 1) }
+
+	tests := []struct {
+		name     string
+		boundary ClockTime
+		dayEnd   ClockTime
+		checkIn  string
+		want     time.Time
+	}{
+		{name: "evening", dayEnd: ClockTime{Hour: 20}, checkIn: "08:00", want: clock(t, "20:00")},
+		{name: "midnight", dayEnd: ClockTime{Hour: 24}, checkIn: "08:00", want: nextDay("00:00")},
+		{name: "night shift", boundary: ClockTime{Hour: 12}, dayEnd: ClockTime{Hour: 6}, checkIn: "22:00", want: nextDay("06:00")},
+		{name: "day end at the day boundary", boundary: ClockTime{Hour: 6}, dayEnd: ClockTime{Hour: 6}, checkIn: "22:00", want: nextDay("06:00")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := Config{DayBoundary: tt.boundary, DayEnd: tt.dayEnd, WorkDuration: 8 * time.Hour}
+			conf.StartDay(clock(t, tt.checkIn))
+			if !conf.EndOfDay.Equal(tt.want) {
+				t.Errorf("EndOfDay = %s, want %s", conf.EndOfDay, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,360 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+func (app *App) configCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Show, change and validate the settings",
+		Example: "  go-home config get ui.width\n" +
+			"  go-home config set work_duration 7h30m\n" +
+			"  go-home config show --effective",
+	}
+
+	cmd.AddCommand(
+		app.configGetCommand(),
+		app.configSetCommand(),
+		app.configEditCommand(),
+		app.configShowCommand(),
+		app.configValidateCommand(),
+	)
+
+	return cmd
+}
+
+func (app *App) configGetCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "get <setting>",
+		Short: "Print the effective value of a setting (e.g. ui.width)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if app.initErr != nil {
+				return app.initErr
+			}
+
+			return writeSetting(cmd.OutOrStdout(), app.conf, args[0])
+		},
+	}
+}
+
+func (app *App) configSetCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "set <setting> <value>",
+		Short: "Change a setting in the configuration file",
+		Long: "Change a setting in the configuration file. The value is parsed as YAML,\n" +
+			"so lists can be given as [10m, 5m]. The file is only written if the new\n" +
+			"configuration is valid. A running widget picks up the change automatically.",
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := cmd.Flag("config").Value.String()
+			statePath := cmd.Flag("state").Value.String()
+			return setConfig(path, statePath, args[0], args[1])
+		},
+	}
+}
+
+func (app *App) configEditCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "edit",
+		Short: "Open the configuration file in $EDITOR and validate it when saved",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			path := cmd.Flag("config").Value.String()
+			statePath := cmd.Flag("state").Value.String()
+			return editConfig(os.Stdin, cmd.OutOrStdout(), path, statePath)
+		},
+	}
+}
+
+func (app *App) configShowCommand() *cobra.Command {
+	var effective bool
+	cmd := &cobra.Command{
+		Use:   "show",
+		Short: "Print the configuration file",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if !effective {
+				path := cmd.Flag("config").Value.String()
+				data, err := readConfigFile(path)
+				if err != nil {
+					return err
+				}
+				if data == nil {
+					app.logger.Info("Config file does not exist yet, all settings have their default value", zap.String("path", path))
+				}
+				_, err = cmd.OutOrStdout().Write(data)
+				return err
+			}
+
+			if app.initErr != nil {
+				return app.initErr
+			}
+
+			data, err := yaml.Marshal(app.conf)
+			if err != nil {
+				return errors.Wrap(err, "failed to encode config as YAML")
+			}
+			_, err = cmd.OutOrStdout().Write(data)
+			return err
+		},
+	}
+
+	cmd.Flags().BoolVar(&effective, "effective", false, "print all settings including the defaults")
+	return cmd
+}
+
+func (app *App) configValidateCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "validate",
+		Short: "Check the configuration file and report all problems",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			// The configuration is read again instead of using app.initErr
+			// so all problems are reported and not only the first one.
+			path := cmd.Flag("config").Value.String()
+			statePath := cmd.Flag("state").Value.String()
+			return validateConfig(cmd.OutOrStdout(), path, statePath)
+		},
+	}
+}
+
+// validateConfig prints all problems of the settings file. Problems which
+// would prevent Go Home from starting are returned as error.
+func validateConfig(w io.Writer, path, statePath string) error {
+	data, err := readConfigFile(path)
+	if err != nil {
+		return err
+	}
+
+	conf, err := loadSettings(data, path, statePath)
+	problems, ok := err.(configError)
+	if err != nil && !ok {
+		return err
+	}
+
+	for _, p := range problems {
+		fmt.Fprintf(w, "%s: %s\n", path, p)
+	}
+	if len(problems) > 0 {
+		return errors.New("configuration is invalid")
+	}
+
+	for _, p := range conf.warnings {
+		fmt.Fprintf(w, "%s: warning: %s\n", path, p)
+	}
+
+	fmt.Fprintf(w, "%s is valid\n", path)
+	return nil
+}
+
+// writeSetting prints the value of the setting at the given path. Sections
+// like "ui" are printed as YAML.
+func writeSetting(w io.Writer, conf Config, path string) error {
+	var root yaml.Node
+	err := root.Encode(conf)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode config as YAML")
+	}
+
+	_, value := findYAMLPath(&root, path)
+	if value == nil {
+		return errors.Errorf("unknown setting %q", path)
+	}
+
+	if value.Kind == yaml.ScalarNode {
+		_, err = fmt.Fprintln(w, value.Value)
+		return err
+	}
+
+	data, err := yaml.Marshal(value)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode setting as YAML")
+	}
+
+	_, err = w.Write(data)
+	return err
+}
+
+// setConfig changes a single setting in the configuration file. The comments
+// and layout of the file are kept. If the file does not exist yet, it is
+// created with the default settings.
+func setConfig(path, statePath, setting, value string) error {
+	data, err := readConfigFile(path)
+	if err != nil {
+		return err
+	}
+	if data == nil {
+		data, err = defaultSettings(path, statePath)
+		if err != nil {
+			return err
+		}
+	}
+
+	doc, err := parseYAMLDocument(data)
+	if err != nil {
+		// Start from scratch if the file is empty.
+		doc = &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{
+			{Kind: yaml.MappingNode, Tag: "!!map"},
+		}}
+	}
+
+	var v yaml.Node
+	err = yaml.Unmarshal([]byte(value), &v)
+	if err != nil {
+		return errors.Wrap(err, "invalid value")
+	}
+
+	n := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str"} // empty value
+	if len(v.Content) > 0 {
+		n = v.Content[0]
+	}
+
+	err = setYAMLPath(doc.Content[0], setting, n)
+	if err != nil {
+		return err
+	}
+
+	data, err = encodeYAMLDocument(doc)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode config as YAML")
+	}
+
+	_, err = loadSettings(data, path, statePath)
+	if err != nil {
+		return err
+	}
+
+	return errors.Wrap(writeFileAtomic(path, data, 0644), "failed to save config")
+}
+
+// editConfig opens a copy of the configuration file in the editor of the
+// user. The file is only replaced if the changes are valid. Otherwise the
+// user can fix the problems or discard the changes. If the file does not
+// exist yet, editing starts with the default settings.
+func editConfig(in io.Reader, out io.Writer, path, statePath string) error {
+	original, err := readConfigFile(path)
+	if err != nil {
+		return err
+	}
+	if original == nil {
+		original, err = defaultSettings(path, statePath)
+		if err != nil {
+			return err
+		}
+	}
+
+	tmp, err := ioutil.TempFile("", "go-home-*.yml")
+	if err != nil {
+		return errors.Wrap(err, "failed to create temporary file")
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	answers := bufio.NewReader(in)
+	data := original
+	for {
+		err = ioutil.WriteFile(tmp.Name(), data, 0600)
+		if err != nil {
+			return errors.Wrap(err, "failed to write temporary file")
+		}
+
+		err = runEditor(tmp.Name())
+		if err != nil {
+			return err
+		}
+
+		data, err = ioutil.ReadFile(tmp.Name())
+		if err != nil {
+			return errors.Wrap(err, "failed to read temporary file")
+		}
+
+		if bytes.Equal(data, original) {
+			fmt.Fprintln(out, "No changes")
+			return nil
+		}
+
+		_, err = loadSettings(data, path, statePath)
+		if err == nil {
+			break
+		}
+
+		fmt.Fprintln(out, err)
+		fmt.Fprint(out, "Edit again? [Y/n] ")
+		answer, _ := answers.ReadString('\n')
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "", "y", "yes":
+		default:
+			return errors.New("changes discarded")
+		}
+	}
+
+	return errors.Wrap(writeFileAtomic(path, data, 0644), "failed to save config")
+}
+
+// readConfigFile reads the settings file. A file which does not exist yet is
+// returned as empty since Go Home uses the defaults until it is written.
+func readConfigFile(path string) ([]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	return data, errors.Wrap(err, "failed to read config file")
+}
+
+// defaultSettings returns the settings file which Go Home writes on its first
+// start.
+func defaultSettings(path, statePath string) ([]byte, error) {
+	conf, err := loadSettings(nil, path, statePath)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := yaml.Marshal(conf)
+	return data, errors.Wrap(err, "failed to encode config as YAML")
+}
+
+// runEditor opens the file in $VISUAL or $EDITOR and waits until the editor
+// is closed. The variables may contain arguments like "code --wait".
+func runEditor(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	cmd := exec.Command("sh", "-c", editor+` "$1"`, "sh", path)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	return errors.Wrap(cmd.Run(), "failed to run editor")
+}
+
+// loadSettings loads data as settings file at path together with the current
+// state without logging anything.
+func loadSettings(data []byte, path, statePath string) (Config, error) {
+	var state io.Reader
+	if f, err := os.Open(statePath); err == nil {
+		defer f.Close()
+		state = f
+	}
+
+	return LoadConfig(bytes.NewReader(data), state, nil, zap.NewNop(), path, statePath, false)
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+func (app *App) checkInCommand() *cobra.Command {
+	var at string
+	cmd := &cobra.Command{
+		Use:   "checkin",
+		Short: "Correct the check-in time of today",
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return app.correct("checkin", at)
+		},
+	}
+
+	cmd.Flags().StringVar(&at, "at", "", `check-in time as "hh:mm" (default now)`)
+	return cmd
+}
+
+func (app *App) checkOutCommand() *cobra.Command {
+	var at string
+	cmd := &cobra.Command{
+		Use:   "checkout",
+		Short: "Set the time at which you finished working today",
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return app.correct("checkout", at)
+		},
+	}
+
+	cmd.Flags().StringVar(&at, "at", "", `check-out time as "hh:mm" (default now)`)
+	return cmd
+}
+
+func (app *App) breakCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "break",
+		Short: "Manage the breaks of today",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:     "add <hh:mm>-<hh:mm>",
+		Short:   "Add a break that was not detected automatically",
+		Example: "  go-home break add 12:00-12:45",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			return app.correct("break", args[0])
+		},
+	})
+
+	return cmd
+}
+
+// corrections are the manual corrections of the current day by name. Each
+// takes a single argument as given on the command line.
+var corrections = map[string]func(conf *Config, arg string, now time.Time) error{
+	"checkin":  func(conf *Config, at string, now time.Time) error { return conf.correctCheckIn(at, now) },
+	"checkout": func(conf *Config, at string, now time.Time) error { return conf.correctCheckOut(at, now) },
+	"break":    func(conf *Config, b string, _ time.Time) error { return conf.addBreak(b) },
+}
+
+// correct applies a manual correction to the current day. If the widget is
+// running, it applies the correction itself. Otherwise its state would
+// overwrite the changes, e.g. when it saves a break which it detected while
+// this command was running.
+func (app *App) correct(name, arg string) error {
+	if app.initErr != nil {
+		return app.initErr
+	}
+
+	err := sendCommand(strings.TrimSpace("correct " + name + " " + arg))
+	if err != errNotRunning {
+		if err == nil {
+			app.logger.Info("Updated work day of running widget")
+		}
+		return err
+	}
+
+	// A correction on a new morning starts the day first so it is recorded
+	// and the day_start hooks run as if the widget had been started.
+	err = app.commitDay()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	err = corrections[name](&app.conf, arg, now)
+	if err != nil {
+		return err
+	}
+
+	app.conf.UpdateCheckOut(now)
+	err = app.conf.SaveState()
+	if err != nil {
+		return err
+	}
+
+	app.logger.Info("Updated work day", zap.Object("config", app.conf))
+	return app.record(app.conf.CheckedOut)
+}
+
+// applyCorrection applies a manual correction to the day of the running
+// widget. It must be called from the run loop. If fn rejects the correction,
+// nothing is changed and its error is returned as invalid so callers can
+// tell it apart from failing to save the state.
+func (app *App) applyCorrection(fn func(conf *Config, now time.Time) error) (invalid, err error) {
+	now := time.Now()
+	app.wake(now) // corrections after the day boundary are meant for the new day
+	conf := app.conf
+	invalid = fn(&conf, now)
+	if invalid != nil {
+		return invalid, nil
+	}
+
+	conf.UpdateCheckOut(now)
+	err = conf.SaveState()
+	if err != nil {
+		return nil, err
+	}
+
+	app.conf = conf
+	app.render.Update(app.conf)
+	app.logger.Info("Updated work day", zap.Object("config", app.conf))
+	return nil, app.record(app.conf.CheckedOut)
+}
+
+// correctCheckIn sets the check-in time to the given "hh:mm" time of today.
+// An empty string means now.
+func (conf *Config) correctCheckIn(at string, now time.Time) error {
+	t, err := conf.parseClockTime(at, conf.WorkDate(now), now)
+	if err != nil {
+		return errors.Wrap(err, "invalid check-in time")
+	}
+
+	if !conf.CheckedOut.IsZero() && !t.Before(conf.CheckedOut) {
+		return errors.Errorf("check-in must be before check-out at %s", conf.CheckedOut.Format("15:04"))
+	}
+
+	conf.CheckIn = t
+	return nil
+}
+
+// correctCheckOut finishes the current day at the given "hh:mm" time. An
+// empty string means now.
+func (conf *Config) correctCheckOut(at string, now time.Time) error {
+	t, err := conf.parseClockTime(at, conf.WorkDate(conf.CheckIn), now)
+	if err != nil {
+		return errors.Wrap(err, "invalid check-out time")
+	}
+
+	if !t.After(conf.CheckIn) {
+		return errors.Errorf("check-out must be after check-in at %s", conf.CheckIn.Format("15:04"))
+	}
+
+	conf.CheckedOut = t
+	return nil
+}
+
+// addBreak adds a break formatted as "hh:mm-hh:mm" to the current day.
+func (conf *Config) addBreak(s string) error {
+	b, err := conf.parseBreak(s, conf.WorkDate(conf.CheckIn))
+	if err != nil {
+		return err
+	}
+
+	conf.Breaks = append(conf.Breaks, b)
+	return nil
+}
+
+// parseClockTime parses a "hh:mm" string into a time during the work day
+// starting on the given date. An empty string is interpreted as now.
+func (conf Config) parseClockTime(s string, date, now time.Time) (time.Time, error) {
+	if s == "" {
+		return now.Round(time.Second), nil
+	}
+
+	var t ClockTime
+	err := t.UnmarshalText([]byte(s))
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return conf.TimeOn(date, t), nil
+}
+
+// parseBreak parses a break formatted as "hh:mm-hh:mm" during the work day
+// starting on the given date.
+func (conf Config) parseBreak(s string, date time.Time) (Break, error) {
+	parts := strings.Split(s, "-")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return Break{}, errors.Errorf(`break %q is not formatted as "hh:mm-hh:mm"`, s)
+	}
+
+	start, err := conf.parseClockTime(parts[0], date, time.Time{})
+	if err != nil {
+		return Break{}, errors.Wrap(err, "invalid start of break")
+	}
+
+	end, err := conf.parseClockTime(parts[1], date, time.Time{})
+	if err != nil {
+		return Break{}, errors.Wrap(err, "invalid end of break")
+	}
+
+	if !end.After(start) {
+		return Break{}, errors.New("break must end after it started")
+	}
+
+	return Break{Start: start, End: end, Source: "manual"}, nil
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func (app *App) exportCommand() *cobra.Command {
+	var (
+		opts reportOptions
+		file string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "export {ics|csv}",
+		Short: "Export the recorded work days to iCalendar or CSV",
+		Example: "  go-home export ics --file work.ics\n" +
+			"  go-home export csv --from 2019-06-01 --to 2019-06-30",
+		Args:      cobra.ExactValidArgs(1),
+		ValidArgs: []string{"ics", "csv"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if app.initErr != nil {
+				return app.initErr
+			}
+
+			w := cmd.OutOrStdout()
+			if file != "" {
+				f, err := os.Create(file)
+				if err != nil {
+					return errors.Wrap(err, "failed to create export file")
+				}
+				defer f.Close()
+				w = f
+			}
+
+			return app.export(w, args[0], opts, time.Now())
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&opts.from, "from", "", "first day to export (YYYY-MM-DD, default all days)")
+	flags.StringVar(&opts.to, "to", "", "last day to export (YYYY-MM-DD, default today)")
+	flags.StringVarP(&file, "file", "f", "", "write to this file instead of stdout")
+
+	return cmd
+}
+
+func (app *App) export(w io.Writer, format string, opts reportOptions, now time.Time) error {
+	if opts.from == "" {
+		opts.from = "0000-01-01"
+	}
+
+	from, to, err := opts.dateRange(app.conf.WorkDate(now))
+	if err != nil {
+		return err
+	}
+
+	days, err := app.journal.Days()
+	if err != nil {
+		return errors.Wrap(err, "failed to read journal")
+	}
+
+	entries := reportEntries(days, app.conf.calendar, from, to, app.conf.WorkDate(now))
+	switch format {
+	case "ics":
+		return writeICS(w, entries, now)
+	case "csv":
+		return writeReportCSV(w, entries)
+	default:
+		return errors.Errorf("unknown export format %q", format)
+	}
+}
+
+// writeICS writes each work day as an event from check-in until the day was
+// finished. Days which are not finished yet end at the computed check-out.
+func writeICS(w io.Writer, entries []reportEntry, now time.Time) error {
+	bw := bufio.NewWriter(w)
+	line := func(format string, args ...interface{}) {
+		bw.WriteString(foldICS(fmt.Sprintf(format, args...)))
+		bw.WriteString("\r\n")
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//go-home//go-home//EN")
+	line("CALSCALE:GREGORIAN")
+
+	for _, e := range entries {
+		end := e.exitTime()
+		summary := "Work (" + formatDuration(time.Duration(e.Worked)) + ")"
+		if end.IsZero() {
+			end = e.CheckOut
+			summary = "Work"
+		}
+
+		line("BEGIN:VEVENT")
+		line("UID:%s@go-home", e.Date)
+		line("DTSTAMP:%s", formatICSTime(now))
+		line("DTSTART:%s", formatICSTime(e.CheckIn))
+		line("DTEND:%s", formatICSTime(end))
+		line("SUMMARY:%s", escapeICS(summary))
+		line("DESCRIPTION:%s", escapeICS(fmt.Sprintf("Breaks: %s\nWorked: %s\nOvertime: %s",
+			e.formatDuration(e.Breaks),
+			e.formatDuration(e.Worked),
+			e.formatDuration(e.Overtime),
+		)))
+		line("END:VEVENT")
+	}
+
+	line("END:VCALENDAR")
+	return bw.Flush()
+}
+
+func formatICSTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// escapeICS escapes a TEXT value (RFC 5545, 3.3.11).
+func escapeICS(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ",", `\,`, ";", `\;`, "\n", `\n`).Replace(s)
+}
+
+// foldICS splits content lines which are longer than 75 octets as required by
+// RFC 5545, 3.1. This is the counterpart of unfoldICS.
+func foldICS(line string) string {
+	var b strings.Builder
+	max := 75
+	for len(line) > max {
+		i := max
+		for i > 0 && line[i]&0xC0 == 0x80 { // do not split UTF-8 sequences
+			i--
+		}
+		b.WriteString(line[:i])
+		b.WriteString("\r\n ")
+		line = line[i:]
+		max = 74 // the leading space counts towards the line length
+	}
+
+	b.WriteString(line)
+	return b.String()
+}
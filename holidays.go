@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// HolidayConfig configures public holidays and vacation days on which no work
+// is expected.
+type HolidayConfig struct {
+	Files []string  `yaml:"files,omitempty"` // iCalendar (.ics) or YAML files
+	Days  []Holiday `yaml:"days,omitempty"`
+}
+
+// A Holiday is either a single date or a range of dates (e.g. a vacation).
+// In YAML a single date can also be written as plain string.
+type Holiday struct {
+	Date string `yaml:"date,omitempty"` // formatted as "2006-01-02"
+	From string `yaml:"from,omitempty"`
+	To   string `yaml:"to,omitempty"` // inclusive
+	Name string `yaml:"name,omitempty"`
+}
+
+// A Calendar maps dates (formatted as "2006-01-02") to the name of the
+// holiday on that date.
+type Calendar map[string]string
+
+// LoadCalendar reads all configured holidays. Relative file paths are
+// resolved relative to dir.
+func LoadCalendar(conf HolidayConfig, dir string) (Calendar, error) {
+	cal := Calendar{}
+	err := cal.add(conf.Days)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range conf.Files {
+		path = os.ExpandEnv(path)
+		if strings.HasPrefix(path, "~/") {
+			path = filepath.Join(os.Getenv("HOME"), path[2:])
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+
+		if strings.EqualFold(filepath.Ext(path), ".ics") {
+			err = cal.loadICS(path)
+		} else {
+			err = cal.loadYAML(path)
+		}
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to load holidays from %s", path)
+		}
+	}
+
+	return cal, nil
+}
+
+// DayOff returns the name of the holiday on the given date. The boolean is
+// false if the date is a regular work day.
+func (cal Calendar) DayOff(date string) (string, bool) {
+	name, ok := cal[date]
+	return name, ok
+}
+
+func (cal Calendar) add(holidays []Holiday) error {
+	for _, h := range holidays {
+		from, to := h.From, h.To
+		if h.Date != "" {
+			from, to = h.Date, h.Date
+		}
+
+		start, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			return errors.Errorf("invalid holiday date %q: expected YYYY-MM-DD", from)
+		}
+
+		end, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			return errors.Errorf("invalid holiday date %q: expected YYYY-MM-DD", to)
+		}
+
+		cal.addRange(start, end.AddDate(0, 0, 1), h.Name)
+	}
+
+	return nil
+}
+
+// addRange adds all days from start until (but excluding) end.
+func (cal Calendar) addRange(start, end time.Time, name string) {
+	if name == "" {
+		name = "Day off"
+	}
+
+	for d := start; d.Before(end); d = d.AddDate(0, 0, 1) {
+		cal[d.Format("2006-01-02")] = name
+	}
+}
+
+// loadYAML reads a YAML file containing a list of holidays.
+func (cal Calendar) loadYAML(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var holidays []Holiday
+	err = yaml.NewDecoder(f).Decode(&holidays)
+	if err != nil {
+		return errors.Wrap(err, "failed to decode YAML")
+	}
+
+	return cal.add(holidays)
+}
+
+// loadICS reads all events of an iCalendar file as holidays. Recurring
+// events (RRULE) are not expanded, so each holiday must be its own event.
+func (cal Calendar) loadICS(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var (
+		inEvent    bool
+		start, end time.Time
+		summary    string
+		lineNumber int
+	)
+
+	lines, err := unfoldICS(f)
+	if err != nil {
+		return err
+	}
+
+	for _, line := range lines {
+		lineNumber = line.number
+		name, params, value := splitICSLine(line.text)
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			inEvent = true
+			start, end, summary = time.Time{}, time.Time{}, ""
+		case name == "END" && value == "VEVENT":
+			inEvent = false
+			if start.IsZero() {
+				return errors.Errorf("line %d: event without DTSTART", lineNumber)
+			}
+			if end.IsZero() || !end.After(start) {
+				end = start.AddDate(0, 0, 1)
+			}
+			cal.addRange(start, end, summary)
+		case inEvent && name == "DTSTART":
+			start, err = parseICSDate(value, params)
+			start = startOfDay(start)
+		case inEvent && name == "DTEND":
+			// The end is exclusive, but a timed event which ends during a
+			// day (e.g. a vacation until noon) includes that day.
+			end, err = parseICSDate(value, params)
+			if day := startOfDay(end); end.After(day) {
+				end = day.AddDate(0, 0, 1)
+			}
+		case inEvent && name == "SUMMARY":
+			summary = strings.NewReplacer(`\,`, ",", `\;`, ";", `\n`, " ", `\\`, `\`).Replace(value)
+		}
+
+		if err != nil {
+			return errors.Wrapf(err, "line %d", lineNumber)
+		}
+	}
+
+	return nil
+}
+
+type icsLine struct {
+	number int
+	text   string
+}
+
+// unfoldICS reads all content lines of an iCalendar file. Long lines are
+// folded by starting the continuation with a space or tab (RFC 5545, 3.1).
+func unfoldICS(f *os.File) ([]icsLine, error) {
+	var lines []icsLine
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(text, " ") || strings.HasPrefix(text, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1].text += text[1:]
+			continue
+		}
+		if text != "" {
+			lines = append(lines, icsLine{number: n, text: text})
+		}
+	}
+
+	return lines, scanner.Err()
+}
+
+// splitICSLine splits a content line like "DTSTART;VALUE=DATE:20191225" into
+// its name, parameters and value.
+func splitICSLine(line string) (name, params, value string) {
+	i := strings.Index(line, ":")
+	if i < 0 {
+		return strings.ToUpper(line), "", ""
+	}
+
+	name, value = line[:i], line[i+1:]
+	if j := strings.Index(name, ";"); j >= 0 {
+		name, params = name[:j], name[j+1:]
+	}
+
+	return strings.ToUpper(name), params, value
+}
+
+// parseICSDate parses an iCalendar DATE or DATE-TIME value in the local time
+// zone. Dates are returned as the start of the day.
+func parseICSDate(value, params string) (time.Time, error) {
+	loc := time.Local
+	for _, p := range strings.Split(params, ";") {
+		if strings.HasPrefix(p, "TZID=") {
+			if l, err := time.LoadLocation(strings.TrimPrefix(p, "TZID=")); err == nil {
+				loc = l
+			}
+		}
+	}
+
+	var t time.Time
+	var err error
+	switch {
+	case len(value) == 8:
+		t, err = time.ParseInLocation("20060102", value, time.Local)
+	case strings.HasSuffix(value, "Z"):
+		t, err = time.Parse("20060102T150405Z", value)
+		t = t.In(time.Local)
+	default:
+		t, err = time.ParseInLocation("20060102T150405", value, loc)
+		t = t.In(time.Local)
+	}
+
+	if err != nil {
+		return time.Time{}, errors.Errorf("invalid date %q", value)
+	}
+
+	return t, nil
+}
+
+// startOfDay returns midnight of the day of t.
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+func (h *Holiday) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*h = Holiday{Date: value.Value}
+		return nil
+	}
+
+	type plain Holiday // prevent recursion
+	return value.Decode((*plain)(h))
+}
+
+func (h Holiday) MarshalYAML() (interface{}, error) {
+	if h.From == "" && h.To == "" && h.Name == "" {
+		return h.Date, nil
+	}
+
+	type plain Holiday // prevent recursion
+	return plain(h), nil
+}
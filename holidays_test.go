@@ -0,0 +1,173 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseICSDate(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("time zone database is not available")
+	}
+
+	tests := []struct {
+		name   string
+		value  string
+		params string
+		want   time.Time
+		err    bool
+	}{
+		{
+			name:   "date",
+			value:  "20191225",
+			params: "VALUE=DATE",
+			want:   time.Date(2019, 12, 25, 0, 0, 0, 0, time.Local),
+		},
+		{
+			name:  "local date-time",
+			value: "20191225T093000",
+			want:  time.Date(2019, 12, 25, 9, 30, 0, 0, time.Local),
+		},
+		{
+			name:  "UTC date-time",
+			value: "20191225T230000Z",
+			want:  time.Date(2019, 12, 25, 23, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "date-time with time zone",
+			value:  "20191225T090000",
+			params: "TZID=Europe/Berlin",
+			want:   time.Date(2019, 12, 25, 9, 0, 0, 0, berlin),
+		},
+		{
+			name:   "unknown time zone",
+			value:  "20191225T090000",
+			params: "TZID=Nowhere/Special",
+			want:   time.Date(2019, 12, 25, 9, 0, 0, 0, time.Local),
+		},
+		{
+			name:  "invalid date",
+			value: "2019-12-25",
+			err:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseICSDate(tt.value, tt.params)
+			if (err != nil) != tt.err {
+				t.Fatalf("parseICSDate() error = %v, want error = %v", err, tt.err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("parseICSDate() = %s, want %s", got, tt.want)
+			}
+			if !tt.err && got.Location() != time.Local {
+				t.Errorf("parseICSDate() returned time in %s, want local time", got.Location())
+			}
+		})
+	}
+}
+
+func TestLoadICS(t *testing.T) {
+	event := func(lines ...string) string {
+		return "BEGIN:VEVENT\r\n" + strings.Join(lines, "\r\n") + "\r\nEND:VEVENT\r\n"
+	}
+
+	tests := []struct {
+		name   string
+		events []string
+		want   Calendar
+		err    bool
+	}{
+		{
+			name:   "all-day event",
+			events: []string{event("DTSTART;VALUE=DATE:20191225", "DTEND;VALUE=DATE:20191226", "SUMMARY:Christmas")},
+			want:   Calendar{"2019-12-25": "Christmas"},
+		},
+		{
+			name:   "all-day events end before DTEND",
+			events: []string{event("DTSTART;VALUE=DATE:20191224", "DTEND;VALUE=DATE:20191227", "SUMMARY:Vacation")},
+			want:   Calendar{"2019-12-24": "Vacation", "2019-12-25": "Vacation", "2019-12-26": "Vacation"},
+		},
+		{
+			name:   "event without DTEND",
+			events: []string{event("DTSTART;VALUE=DATE:20191225", "SUMMARY:Christmas")},
+			want:   Calendar{"2019-12-25": "Christmas"},
+		},
+		{
+			name:   "timed event within a day",
+			events: []string{event("DTSTART:20191224T090000", "DTEND:20191224T170000", "SUMMARY:Christmas Eve")},
+			want:   Calendar{"2019-12-24": "Christmas Eve"},
+		},
+		{
+			name:   "timed events include the day on which they end",
+			events: []string{event("DTSTART:20191223T120000", "DTEND:20191224T120000", "SUMMARY:Vacation")},
+			want:   Calendar{"2019-12-23": "Vacation", "2019-12-24": "Vacation"},
+		},
+		{
+			name:   "timed event until midnight",
+			events: []string{event("DTSTART:20191224T000000", "DTEND:20191226T000000", "SUMMARY:Vacation")},
+			want:   Calendar{"2019-12-24": "Vacation", "2019-12-25": "Vacation"},
+		},
+		{
+			name: "multiple events",
+			events: []string{
+				event("DTSTART;VALUE=DATE:20191225", "SUMMARY:Christmas"),
+				event("DTSTART;VALUE=DATE:20200101", "SUMMARY:New Year"),
+			},
+			want: Calendar{"2019-12-25": "Christmas", "2020-01-01": "New Year"},
+		},
+		{
+			name:   "folded lines and escaped characters",
+			events: []string{event("DTSTART;VALUE=DATE:20191226", "SUMMARY:Boxing Day\\, also\r\n  St. Stephen's Day")},
+			want:   Calendar{"2019-12-26": "Boxing Day, also St. Stephen's Day"},
+		},
+		{
+			name:   "event without summary",
+			events: []string{event("DTSTART;VALUE=DATE:20191225")},
+			want:   Calendar{"2019-12-25": "Day off"},
+		},
+		{
+			name:   "event without DTSTART",
+			events: []string{event("SUMMARY:Christmas")},
+			err:    true,
+		},
+		{
+			name:   "invalid date",
+			events: []string{event("DTSTART;VALUE=DATE:2019-12-25")},
+			err:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "go-home-test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			path := filepath.Join(dir, "holidays.ics")
+			data := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n" + strings.Join(tt.events, "") + "END:VCALENDAR\r\n"
+			err = ioutil.WriteFile(path, []byte(data), 0600)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cal := Calendar{}
+			err = cal.loadICS(path)
+			if (err != nil) != tt.err {
+				t.Fatalf("loadICS() error = %v, want error = %v", err, tt.err)
+			}
+			if !tt.err && !reflect.DeepEqual(cal, tt.want) {
+				t.Errorf("loadICS() = %v, want %v", cal, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// The events for which hooks can be configured.
+const (
+	HookDayStart        = "day_start"        // a new work day was started
+	HookCheckOutReached = "checkout_reached" // it is time to go home
+	HookOvertime        = "overtime_every"   // repeated every OvertimeInterval after check-out
+	HookDayEnd          = "day_end"          // the widget was closed or the day ended at the day boundary
+	HookBreakStart      = "break_start"      // a break was detected
+	HookBreakEnd        = "break_end"        // a detected break has ended
+)
+
+const (
+	defaultHookTimeout          = 30 * time.Second
+	defaultHookOvertimeInterval = time.Hour
+)
+
+// HookConfig maps events to shell commands which are executed when the event
+// happens. Each command is run via "sh -c" and receives the details of the
+// event in GO_HOME_* environment variables (see hookEnv).
+type HookConfig struct {
+	Timeout          time.Duration `yaml:"timeout,omitempty"`           // per command (default 30s)
+	OvertimeInterval time.Duration `yaml:"overtime_interval,omitempty"` // default 1h
+
+	DayStart        Commands `yaml:"day_start,omitempty"`
+	CheckOutReached Commands `yaml:"checkout_reached,omitempty"`
+	Overtime        Commands `yaml:"overtime_every,omitempty"`
+	DayEnd          Commands `yaml:"day_end,omitempty"`
+	BreakStart      Commands `yaml:"break_start,omitempty"`
+	BreakEnd        Commands `yaml:"break_end,omitempty"`
+}
+
+// Commands is a list of shell commands. In YAML a single command can also be
+// written as plain string.
+type Commands []string
+
+func (c HookConfig) check(ch *configChecker) {
+	ch.notNegative("hooks.timeout", c.Timeout)
+	ch.notNegative("hooks.overtime_interval", c.OvertimeInterval)
+}
+
+func (c HookConfig) commands(event string) Commands {
+	switch event {
+	case HookDayStart:
+		return c.DayStart
+	case HookCheckOutReached:
+		return c.CheckOutReached
+	case HookOvertime:
+		return c.Overtime
+	case HookDayEnd:
+		return c.DayEnd
+	case HookBreakStart:
+		return c.BreakStart
+	case HookBreakEnd:
+		return c.BreakEnd
+	default:
+		return nil
+	}
+}
+
+// runHooks executes all commands of the given event in the background. The
+// commands of a single event run one after another in the configured order.
+// Additional environment variables can be passed as "KEY=value".
+func (app *App) runHooks(event string, now time.Time, env ...string) {
+	cmds := app.conf.Hooks.commands(event)
+	if len(cmds) == 0 {
+		return
+	}
+
+	timeout := app.conf.Hooks.Timeout
+	if timeout == 0 {
+		timeout = defaultHookTimeout
+	}
+
+	env = append(append(os.Environ(), hookEnv(event, app.conf, now)...), env...)
+	logger := app.logger.With(zap.String("event", event))
+
+	app.hooks.Add(1)
+	go func() {
+		defer app.hooks.Done()
+		for _, cmd := range cmds {
+			runHook(logger, cmd, env, timeout)
+		}
+	}()
+}
+
+// runDayEndHooks runs the day_end hooks of the current day which ended at the
+// given time.
+func (app *App) runDayEndHooks(exit time.Time) {
+	app.runHooks(HookDayEnd, exit, "GO_HOME_EXIT="+exit.Format(time.RFC3339))
+}
+
+// runHook executes a single command and logs its result. If the command does
+// not finish in time, it is killed together with all of its child processes.
+func runHook(logger *zap.Logger, command string, env []string, timeout time.Duration) {
+	logger = logger.With(zap.String("command", command))
+	logger.Debug("Running hook")
+
+	var out bytes.Buffer
+	cmd := exec.Command("/bin/sh", "-c", command)
+	cmd.Env = env
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	err := cmd.Start()
+	if err != nil {
+		logger.Error("Failed to start hook", zap.Error(err))
+		return
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	select {
+	case err = <-done:
+	case <-time.After(timeout):
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		<-done
+		err = errors.Errorf("timeout after %s", timeout)
+	}
+
+	if err != nil {
+		logger.Error("Hook failed", zap.Error(err), zap.String("output", out.String()))
+		return
+	}
+
+	logger.Debug("Hook finished", zap.String("output", out.String()))
+}
+
+// hookEnv returns the environment variables which describe the current day.
+// Points in time are formatted as RFC 3339 and durations as seconds.
+func hookEnv(event string, conf Config, now time.Time) []string {
+	s := NewStatus(conf, now)
+	env := []string{
+		"GO_HOME_EVENT=" + event,
+		"GO_HOME_TIME=" + now.Format(time.RFC3339),
+		"GO_HOME_DATE=" + conf.WorkDate(conf.CheckIn).Format("2006-01-02"),
+		"GO_HOME_CHECK_IN=" + s.CheckIn.Format(time.RFC3339),
+		"GO_HOME_CHECK_OUT=" + s.CheckOut.Format(time.RFC3339),
+		"GO_HOME_END_OF_DAY=" + s.EndOfDay.Format(time.RFC3339),
+		"GO_HOME_REMAINING=" + seconds(s.Remaining),
+		"GO_HOME_OVERTIME=" + seconds(s.Overtime),
+	}
+
+	if conf.Flexitime.Enabled {
+		env = append(env, "GO_HOME_BALANCE="+seconds(s.Balance))
+	}
+	if s.DayOff != "" {
+		env = append(env, "GO_HOME_DAY_OFF="+s.DayOff)
+	}
+
+	return env
+}
+
+func seconds(d Duration) string {
+	return strconv.Itoa(int(time.Duration(d) / time.Second))
+}
+
+// handleTimedHooks runs the hooks of all events which happened between the
+// previous frame and now.
+func (app *App) handleTimedHooks(since, now time.Time) {
+	if app.afterHours || app.conf.Today.Holiday != "" || !app.conf.CheckedOut.IsZero() {
+		return
+	}
+
+	checkOut := app.conf.CheckOut
+	if crossed(checkOut, since, now) {
+		app.runHooks(HookCheckOutReached, now)
+	}
+
+	interval := app.conf.Hooks.OvertimeInterval
+	if interval == 0 {
+		interval = defaultHookOvertimeInterval
+	}
+
+	if n := now.Sub(checkOut) / interval; n > 0 && crossed(checkOut.Add(n*interval), since, now) {
+		app.runHooks(HookOvertime, now)
+	}
+}
+
+// crossed returns true if t lies after since and not after now.
+func crossed(t, since, now time.Time) bool {
+	return t.After(since) && !t.After(now)
+}
+
+func (c *Commands) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*c = Commands{value.Value}
+		return nil
+	}
+
+	var cmds []string
+	err := value.Decode(&cmds)
+	*c = cmds
+	return err
+}
+
+func (c Commands) MarshalYAML() (interface{}, error) {
+	if len(c) == 1 {
+		return c[0], nil
+	}
+
+	return []string(c), nil
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	"github.com/BurntSushi/xgb"
+	"github.com/BurntSushi/xgb/screensaver"
+	"github.com/BurntSushi/xgb/xproto"
+	"github.com/godbus/dbus/v5"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// An IdleSource reports for how long the user has not used the keyboard or
+// mouse.
+type IdleSource interface {
+	IdleTime() (time.Duration, error)
+	Close() error
+}
+
+// The IdleDetector periodically polls an IdleSource and reports all periods
+// in which the user was idle for longer than a threshold as breaks.
+type IdleDetector struct {
+	source    IdleSource
+	threshold time.Duration
+	interval  time.Duration
+	logger    *zap.Logger
+	events    chan<- BreakEvent
+	done      chan struct{}
+}
+
+// NewIdleDetector starts polling the given IdleSource in the background. All
+// detected breaks are sent to the events channel.
+func NewIdleDetector(source IdleSource, threshold time.Duration, events chan<- BreakEvent, logger *zap.Logger) *IdleDetector {
+	d := &IdleDetector{
+		source:    source,
+		threshold: threshold,
+		interval:  5 * time.Second,
+		logger:    logger,
+		events:    events,
+		done:      make(chan struct{}),
+	}
+
+	go d.run()
+
+	return d
+}
+
+func (d *IdleDetector) run() {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	var idle bool
+	for {
+		select {
+		case <-ticker.C:
+		case <-d.done:
+			return
+		}
+
+		dur, err := d.source.IdleTime()
+		if err != nil {
+			d.logger.Warn("Failed to query idle time", zap.Error(err))
+			continue
+		}
+
+		if idle == (dur >= d.threshold) {
+			continue
+		}
+
+		// The break starts (or ends) with the last input of the user and not
+		// when we noticed the change.
+		idle = !idle
+		e := BreakEvent{Source: "idle", Start: idle, Time: time.Now().Add(-dur)}
+		d.logger.Debug("Detected change of idle state", zap.Bool("idle", idle), zap.Duration("idle_time", dur))
+
+		select {
+		case d.events <- e:
+		case <-d.done:
+			return
+		}
+	}
+}
+
+// Close stops the detector and closes its IdleSource.
+func (d *IdleDetector) Close() error {
+	close(d.done)
+	return d.source.Close()
+}
+
+// X11IdleSource uses the MIT-SCREEN-SAVER extension of the X server to
+// determine the idle time.
+type X11IdleSource struct {
+	conn *xgb.Conn
+	root xproto.Window
+}
+
+func NewX11IdleSource() (*X11IdleSource, error) {
+	conn, err := xgb.NewConn()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to connect to X server")
+	}
+
+	err = screensaver.Init(conn)
+	if err != nil {
+		conn.Close()
+		return nil, errors.Wrap(err, "failed to initialize screen saver extension")
+	}
+
+	return &X11IdleSource{
+		conn: conn,
+		root: xproto.Setup(conn).DefaultScreen(conn).Root,
+	}, nil
+}
+
+func (s *X11IdleSource) IdleTime() (time.Duration, error) {
+	info, err := screensaver.QueryInfo(s.conn, xproto.Drawable(s.root)).Reply()
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to query screen saver info")
+	}
+
+	return time.Duration(info.MsSinceUserInput) * time.Millisecond, nil
+}
+
+func (s *X11IdleSource) Close() error {
+	s.conn.Close()
+	return nil
+}
+
+// LogindIdleSource uses the IdleHint of the logind session to determine the
+// idle time. This is less precise than the X11IdleSource since the idle hint
+// is typically only set after a timeout of the desktop environment.
+type LogindIdleSource struct {
+	conn    *dbus.Conn
+	session dbus.BusObject
+}
+
+func NewLogindIdleSource(conn *dbus.Conn) (*LogindIdleSource, error) {
+	path, err := logindSessionPath(conn)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to determine logind session")
+	}
+
+	return &LogindIdleSource{
+		conn:    conn,
+		session: conn.Object("org.freedesktop.login1", path),
+	}, nil
+}
+
+func (s *LogindIdleSource) IdleTime() (time.Duration, error) {
+	hint, err := s.session.GetProperty(logindSessionInterface + ".IdleHint")
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to get IdleHint")
+	}
+
+	if idle, _ := hint.Value().(bool); !idle {
+		return 0, nil
+	}
+
+	since, err := s.session.GetProperty(logindSessionInterface + ".IdleSinceHint")
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to get IdleSinceHint")
+	}
+
+	micros, _ := since.Value().(uint64)
+	return time.Since(time.Unix(0, int64(micros)*int64(time.Microsecond))), nil
+}
+
+func (s *LogindIdleSource) Close() error {
+	return s.conn.Close()
+}
+
+// detectIdleTime starts an IdleDetector using X11 if available and logind
+// otherwise.
+func (app *App) detectIdleTime(threshold time.Duration) {
+	source, err := newIdleSource()
+	if err != nil {
+		app.logger.Warn("Idle detection is not available", zap.Error(err))
+		return
+	}
+
+	d := NewIdleDetector(source, threshold, app.breaks, app.logger)
+	app.detectors = append(app.detectors, d)
+}
+
+func newIdleSource() (IdleSource, error) {
+	if os.Getenv("DISPLAY") != "" {
+		return NewX11IdleSource()
+	}
+
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to connect to system bus")
+	}
+
+	source, err := NewLogindIdleSource(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return source, nil
+}
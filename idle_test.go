@@ -0,0 +1,124 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// fakeIdleSource returns the idle times which are sent to its channel. Each
+// poll of the IdleDetector blocks until the test sends the next value.
+type fakeIdleSource struct {
+	idle chan idleSample
+}
+
+type idleSample struct {
+	idle time.Duration
+	err  error
+}
+
+func (s *fakeIdleSource) IdleTime() (time.Duration, error) {
+	sample, ok := <-s.idle
+	if !ok {
+		return 0, errors.New("source is closed")
+	}
+
+	return sample.idle, sample.err
+}
+
+func (s *fakeIdleSource) Close() error {
+	close(s.idle)
+	return nil
+}
+
+func TestIdleDetector(t *testing.T) {
+	const threshold = 10 * time.Minute
+	failed := idleSample{err: errors.New("no X server")}
+
+	type event struct {
+		start bool
+		ago   time.Duration // how long before the poll the break started or ended
+	}
+
+	tests := []struct {
+		name    string
+		samples []idleSample
+		events  []event
+	}{
+		{
+			name:    "below threshold",
+			samples: []idleSample{{idle: time.Minute}, {idle: 9 * time.Minute}, {idle: threshold - time.Second}},
+		},
+		{
+			name:    "crossing the threshold",
+			samples: []idleSample{{idle: time.Minute}, {idle: threshold}, {idle: 12 * time.Minute}},
+			events:  []event{{start: true, ago: threshold}},
+		},
+		{
+			name:    "resume after break",
+			samples: []idleSample{{idle: 15 * time.Minute}, {idle: 20 * time.Minute}, {idle: 5 * time.Second}, {idle: time.Minute}},
+			events:  []event{{start: true, ago: 15 * time.Minute}, {start: false, ago: 5 * time.Second}},
+		},
+		{
+			name: "multiple breaks",
+			samples: []idleSample{
+				{idle: 11 * time.Minute}, {idle: 0},
+				{idle: time.Minute}, {idle: 30 * time.Minute}, {idle: 2 * time.Second},
+			},
+			events: []event{
+				{start: true, ago: 11 * time.Minute}, {start: false},
+				{start: true, ago: 30 * time.Minute}, {start: false, ago: 2 * time.Second},
+			},
+		},
+		{
+			name:    "errors do not change the state",
+			samples: []idleSample{{idle: 11 * time.Minute}, failed, {idle: 12 * time.Minute}, failed, {idle: 0}},
+			events:  []event{{start: true, ago: 11 * time.Minute}, {start: false}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := &fakeIdleSource{idle: make(chan idleSample)}
+			events := make(chan BreakEvent, len(tt.samples))
+			d := &IdleDetector{
+				source:    source,
+				threshold: threshold,
+				interval:  time.Millisecond,
+				logger:    zap.NewNop(),
+				events:    events,
+				done:      make(chan struct{}),
+			}
+			go d.run()
+
+			start := time.Now()
+			for _, sample := range tt.samples {
+				source.idle <- sample
+			}
+
+			// The detector has handled the last sample once it polls again.
+			source.idle <- idleSample{idle: tt.samples[len(tt.samples)-1].idle}
+			done := time.Now()
+			d.Close()
+
+			if len(events) != len(tt.events) {
+				t.Fatalf("got %d events, want %d", len(events), len(tt.events))
+			}
+
+			for i, want := range tt.events {
+				e := <-events
+				if e.Source != "idle" || e.Start != want.start {
+					t.Errorf("event %d = %+v, want start = %v", i, e, want.start)
+				}
+
+				// The event time is derived from the idle time at the moment
+				// of the poll which happened during the test.
+				if e.Time.Before(start.Add(-want.ago)) || e.Time.After(done.Add(-want.ago)) {
+					t.Errorf("event %d happened %s ago, want %s", i, time.Since(e.Time), want.ago)
+				}
+			}
+		})
+	}
+}
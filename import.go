@@ -0,0 +1,449 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+// The strategies to resolve days which already exist in the journal.
+const (
+	conflictError     = "error"
+	conflictSkip      = "skip"
+	conflictOverwrite = "overwrite"
+)
+
+type importOptions struct {
+	onConflict string
+	dryRun     bool
+	csv        csvColumns
+	tag        string // only import Timewarrior intervals with this tag
+}
+
+// csvColumns maps the values of a Day to the header names of a CSV file.
+type csvColumns struct {
+	date, checkIn, checkOut, breaks string
+	dateLayout, timeLayout          string
+	comma                           string
+}
+
+// importedDay is a Day together with the line of the input in which it was
+// defined so validation errors can point to it.
+type importedDay struct {
+	Day
+	line int
+}
+
+func (app *App) importCommand() *cobra.Command {
+	var opts importOptions
+	cmd := &cobra.Command{
+		Use:   "import {csv|timewarrior|jsonl} <file>",
+		Short: "Import work days from other time trackers into the journal",
+		Example: "  go-home import csv hours.csv --date-column Datum --check-in-column Start --check-out-column Ende\n" +
+			"  timew export | go-home import timewarrior - --tag work\n" +
+			"  go-home import jsonl old-log.jsonl --on-conflict skip",
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if app.initErr != nil {
+				return app.initErr
+			}
+
+			var r io.Reader = os.Stdin
+			if args[1] != "-" {
+				f, err := os.Open(args[1])
+				if err != nil {
+					return errors.Wrap(err, "failed to open import file")
+				}
+				defer f.Close()
+				r = f
+			}
+
+			return app.importDays(cmd.OutOrStdout(), r, args[0], opts)
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&opts.onConflict, "on-conflict", conflictError, "what to do with days which already exist in the journal (error, skip or overwrite)")
+	flags.BoolVar(&opts.dryRun, "dry-run", false, "only print the days which would be imported")
+	flags.StringVar(&opts.csv.date, "date-column", "date", "CSV column containing the date")
+	flags.StringVar(&opts.csv.checkIn, "check-in-column", "check_in", "CSV column containing the check-in time")
+	flags.StringVar(&opts.csv.checkOut, "check-out-column", "check_out", "CSV column containing the check-out time")
+	flags.StringVar(&opts.csv.breaks, "breaks-column", "breaks", `CSV column containing the break duration (default lunch duration if the column does not exist)`)
+	flags.StringVar(&opts.csv.dateLayout, "date-layout", "2006-01-02", "layout of CSV dates (see https://golang.org/pkg/time/#pkg-constants)")
+	flags.StringVar(&opts.csv.timeLayout, "time-layout", "15:04", "layout of CSV times")
+	flags.StringVar(&opts.csv.comma, "delimiter", ",", "CSV field delimiter")
+	flags.StringVar(&opts.tag, "tag", "", "only import Timewarrior intervals with this tag")
+
+	return cmd
+}
+
+func (app *App) importDays(w io.Writer, r io.Reader, format string, opts importOptions) error {
+	switch opts.onConflict {
+	case conflictError, conflictSkip, conflictOverwrite:
+	default:
+		return errors.Errorf("invalid --on-conflict value %q", opts.onConflict)
+	}
+
+	var (
+		days []importedDay
+		err  error
+	)
+
+	switch format {
+	case "csv":
+		days, err = app.conf.parseCSVDays(r, opts.csv)
+	case "timewarrior":
+		days, err = app.conf.parseTimewarriorDays(r, opts.tag)
+	case "jsonl":
+		days, err = parseJournalDays(r)
+	default:
+		return errors.Errorf("unknown import format %q", format)
+	}
+	if err != nil {
+		return err
+	}
+
+	days, err = app.resolveConflicts(days, opts.onConflict)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range days {
+		fmt.Fprintf(w, "%s %s  %s - %s\n", d.CheckIn.Format("Mon"), d.Date, formatClock(d.CheckIn), formatClock(d.exitTime()))
+		if opts.dryRun {
+			continue
+		}
+
+		err := app.journal.Append(d.Day)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !opts.dryRun {
+		app.logger.Info("Imported work days", zap.Int("days", len(days)))
+	}
+
+	return nil
+}
+
+// resolveConflicts checks the imported days against each other and against
+// the journal. Days which occur multiple times in the input are always an
+// error since we cannot know which one is correct.
+func (app *App) resolveConflicts(days []importedDay, onConflict string) ([]importedDay, error) {
+	seen := map[string]int{}
+	for _, d := range days {
+		if line, ok := seen[d.Date]; ok {
+			return nil, errors.Errorf("line %d: day %s was already defined in line %d", d.line, d.Date, line)
+		}
+		seen[d.Date] = d.line
+	}
+
+	existing, err := app.journal.Days()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read journal")
+	}
+
+	exists := map[string]bool{}
+	for _, d := range existing {
+		exists[d.Date] = true
+	}
+
+	var result []importedDay
+	var conflicts []string
+	for _, d := range days {
+		switch {
+		case !exists[d.Date] || onConflict == conflictOverwrite:
+			result = append(result, d)
+		case onConflict == conflictError:
+			conflicts = append(conflicts, fmt.Sprintf("line %d: day %s already exists in the journal", d.line, d.Date))
+		}
+	}
+
+	if len(conflicts) > 0 {
+		return nil, errors.Errorf("%s\nuse --on-conflict to skip or overwrite existing days", strings.Join(conflicts, "\n"))
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Date < result[j].Date
+	})
+
+	return result, nil
+}
+
+// importedDay computes the journal entry of a finished day in the same way as
+// Config.Day does for the current day.
+func (conf Config) importedDay(checkIn, exit time.Time, breaks time.Duration, line int) (importedDay, error) {
+	if !exit.After(checkIn) {
+		return importedDay{}, errors.Errorf("line %d: check-out must be after check-in", line)
+	}
+
+	date := conf.WorkDate(checkIn)
+	checkOut := checkIn.Add(conf.workDay(date).WorkDuration + breaks)
+	return importedDay{
+		Day: Day{
+			Date:     date.Format("2006-01-02"),
+			CheckIn:  checkIn,
+			CheckOut: checkOut,
+			Exit:     &exit,
+			Breaks:   Duration(breaks),
+			Overtime: Duration(exit.Sub(checkOut)),
+		},
+		line: line,
+	}, nil
+}
+
+// parseCSVDays reads one day per line. The first line must contain the column
+// names. Quoted fields must not span multiple lines.
+func (conf Config) parseCSVDays(r io.Reader, cols csvColumns) ([]importedDay, error) {
+	if len([]rune(cols.comma)) != 1 {
+		return nil, errors.Errorf("invalid CSV delimiter %q", cols.comma)
+	}
+
+	var (
+		days   []importedDay
+		index  map[string]int
+		record []string
+		err    error
+	)
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		cr := csv.NewReader(strings.NewReader(scanner.Text()))
+		cr.Comma = []rune(cols.comma)[0]
+		record, err = cr.Read()
+		if err != nil {
+			return nil, errors.Errorf("line %d: invalid CSV: %v", line, err)
+		}
+
+		if index == nil {
+			index, err = cols.index(record)
+			if err != nil {
+				return nil, errors.Wrapf(err, "line %d", line)
+			}
+			continue
+		}
+
+		d, err := conf.parseCSVRecord(record, index, cols, line)
+		if err != nil {
+			return nil, err
+		}
+		days = append(days, d)
+	}
+
+	return days, errors.Wrap(scanner.Err(), "failed to read CSV")
+}
+
+// index returns the position of each configured column in the header.
+func (cols csvColumns) index(header []string) (map[string]int, error) {
+	index := map[string]int{}
+	for i, name := range header {
+		index[strings.TrimSpace(name)] = i
+	}
+
+	for _, name := range []string{cols.date, cols.checkIn, cols.checkOut} {
+		if _, ok := index[name]; !ok {
+			return nil, errors.Errorf("missing column %q", name)
+		}
+	}
+
+	return index, nil
+}
+
+func (conf Config) parseCSVRecord(record []string, index map[string]int, cols csvColumns, line int) (importedDay, error) {
+	field := func(name string) string {
+		i, ok := index[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	date, err := time.ParseInLocation(cols.dateLayout, field(cols.date), time.Local)
+	if err != nil {
+		return importedDay{}, errors.Errorf("line %d: invalid date %q", line, field(cols.date))
+	}
+
+	parseTime := func(name string) (time.Time, error) {
+		t, err := time.Parse(cols.timeLayout, field(name))
+		if err != nil {
+			return time.Time{}, errors.Errorf("line %d: invalid %s time %q", line, name, field(name))
+		}
+		return conf.TimeOn(date, ClockTime{Hour: t.Hour(), Minute: t.Minute()}), nil
+	}
+
+	checkIn, err := parseTime(cols.checkIn)
+	if err != nil {
+		return importedDay{}, err
+	}
+
+	exit, err := parseTime(cols.checkOut)
+	if err != nil {
+		return importedDay{}, err
+	}
+
+	breaks := conf.workDay(conf.WorkDate(checkIn)).LunchDuration
+	if s := field(cols.breaks); s != "" {
+		breaks, err = parseImportDuration(s)
+		if err != nil {
+			return importedDay{}, errors.Errorf("line %d: invalid break duration %q", line, s)
+		}
+	}
+
+	return conf.importedDay(checkIn, exit, breaks, line)
+}
+
+// parseImportDuration parses durations formatted as "h:mm" (like the report)
+// or as Go duration (e.g. "45m").
+func parseImportDuration(s string) (time.Duration, error) {
+	var ct ClockTime
+	if err := ct.UnmarshalText([]byte(s)); err == nil {
+		return time.Duration(ct.minutes()) * time.Minute, nil
+	}
+
+	return time.ParseDuration(s)
+}
+
+// timewarriorInterval is a single entry of "timew export".
+type timewarriorInterval struct {
+	Start string   `json:"start"`
+	End   string   `json:"end"`
+	Tags  []string `json:"tags"`
+}
+
+// parseTimewarriorDays reads the output of "timew export" which contains one
+// interval per line. All intervals of a work day are combined into a single
+// day and the gaps between them are counted as breaks.
+func (conf Config) parseTimewarriorDays(r io.Reader, tag string) ([]importedDay, error) {
+	type interval struct {
+		start, end time.Time
+		line       int
+	}
+
+	byDate := map[string][]interval{}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSuffix(strings.TrimSpace(scanner.Text()), ",")
+		if text == "" || text == "[" || text == "]" {
+			continue
+		}
+
+		var ti timewarriorInterval
+		err := json.Unmarshal([]byte(text), &ti)
+		if err != nil {
+			return nil, errors.Errorf("line %d: invalid interval: %v", line, err)
+		}
+
+		if tag != "" && !containsString(ti.Tags, tag) {
+			continue
+		}
+		if ti.End == "" {
+			continue // still running
+		}
+
+		start, err := time.Parse("20060102T150405Z", ti.Start)
+		if err != nil {
+			return nil, errors.Errorf("line %d: invalid start %q", line, ti.Start)
+		}
+
+		end, err := time.Parse("20060102T150405Z", ti.End)
+		if err != nil {
+			return nil, errors.Errorf("line %d: invalid end %q", line, ti.End)
+		}
+
+		start, end = start.In(time.Local), end.In(time.Local)
+		date := conf.WorkDate(start).Format("2006-01-02")
+		byDate[date] = append(byDate[date], interval{start: start, end: end, line: line})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read Timewarrior export")
+	}
+
+	var days []importedDay
+	for _, intervals := range byDate {
+		sort.Slice(intervals, func(i, j int) bool {
+			return intervals[i].start.Before(intervals[j].start)
+		})
+
+		first := intervals[0]
+		exit := first.end
+		var breaks time.Duration
+		for _, iv := range intervals[1:] {
+			if iv.start.After(exit) {
+				breaks += iv.start.Sub(exit)
+			}
+			if iv.end.After(exit) {
+				exit = iv.end
+			}
+		}
+
+		d, err := conf.importedDay(first.start, exit, breaks, first.line)
+		if err != nil {
+			return nil, err
+		}
+		days = append(days, d)
+	}
+
+	return days, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseJournalDays reads a journal file of go-home (e.g. from another
+// computer). Multiple entries of the same day are merged like in Journal.Days.
+func parseJournalDays(r io.Reader) ([]importedDay, error) {
+	var days []importedDay
+	index := map[string]int{}
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+
+		d, err := decodeDay(scanner.Bytes())
+		if err != nil {
+			return nil, errors.Errorf("line %d: invalid journal entry: %v", line, err)
+		}
+
+		if d.CheckIn.IsZero() {
+			return nil, errors.Errorf("line %d: missing check_in", line)
+		}
+		if _, err := time.Parse("2006-01-02", d.Date); err != nil {
+			return nil, errors.Errorf("line %d: invalid date %q", line, d.Date)
+		}
+
+		if i, ok := index[d.Date]; ok {
+			days[i].Day = days[i].merge(d)
+			continue
+		}
+
+		index[d.Date] = len(days)
+		days = append(days, importedDay{Day: d, line: line})
+	}
+
+	return days, errors.Wrap(scanner.Err(), "failed to read journal")
+}
@@ -14,21 +14,21 @@ func (app *App) handleInput(win *pixelgl.Window, dt float64) {
 		const speed = 100.0 // pixel per second
 		delta := speed * dt
 		if win.Pressed(pixelgl.KeyRight) {
-			app.conf.UI.WindowPos.X += delta
+			app.conf.WindowPos.X += delta
 		} else if win.Pressed(pixelgl.KeyLeft) {
-			app.conf.UI.WindowPos.X -= delta
+			app.conf.WindowPos.X -= delta
 		}
 
 		if win.Pressed(pixelgl.KeyUp) {
-			app.conf.UI.WindowPos.Y -= delta
+			app.conf.WindowPos.Y -= delta
 		} else if win.Pressed(pixelgl.KeyDown) {
-			app.conf.UI.WindowPos.Y += delta
+			app.conf.WindowPos.Y += delta
 		}
 
 		currPos := app.win.GetPos()
-		if math.Round(currPos.X) != math.Round(app.conf.UI.WindowPos.X) ||
-			math.Round(currPos.Y) != math.Round(app.conf.UI.WindowPos.Y) {
-			app.win.SetPos(app.conf.UI.WindowPos)
+		if math.Round(currPos.X) != math.Round(app.conf.WindowPos.X) ||
+			math.Round(currPos.Y) != math.Round(app.conf.WindowPos.Y) {
+			app.win.SetPos(app.conf.WindowPos)
 		}
 
 	} else {
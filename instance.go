@@ -0,0 +1,345 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/faiface/pixel"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+// errNotRunning is returned by sendCommand if there is no running widget.
+var errNotRunning = errors.New("go-home is not running")
+
+// errAlreadyRunning is returned by lockInstance if another widget is running.
+var errAlreadyRunning = errors.New("go-home is already running")
+
+// A request is a function that must be executed by the run loop of the
+// widget since it changes the state of the application.
+type request struct {
+	fn    func() error
+	reply chan error
+}
+
+// instanceDir returns the directory in which the lock file and the control
+// socket of the running widget are stored and creates it if necessary. The
+// temporary directory is shared by all users, so the directory must belong to
+// the current user and must not be accessible by anybody else. Otherwise
+// another user could create it first and plant their own control socket.
+func instanceDir() (string, error) {
+	dir := filepath.Join(os.Getenv("XDG_RUNTIME_DIR"), "go-home")
+	if os.Getenv("XDG_RUNTIME_DIR") == "" {
+		dir = filepath.Join(os.TempDir(), fmt.Sprintf("go-home-%d", os.Getuid()))
+	}
+
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create runtime directory")
+	}
+
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to check runtime directory")
+	}
+
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	switch {
+	case !info.IsDir():
+		return "", errors.Errorf("runtime directory %s is not a directory", dir)
+	case ok && int(stat.Uid) != os.Getuid():
+		return "", errors.Errorf("runtime directory %s belongs to another user", dir)
+	case info.Mode().Perm()&0077 != 0:
+		return "", errors.Errorf("runtime directory %s must not be accessible by other users (mode %s)", dir, info.Mode().Perm())
+	}
+
+	return dir, nil
+}
+
+func socketPath() (string, error) {
+	dir, err := instanceDir()
+	return filepath.Join(dir, "go-home.sock"), err
+}
+
+// lockInstance makes sure only a single widget is running at the same time.
+// The returned file must be kept open as long as the widget is running.
+func lockInstance() (*os.File, error) {
+	dir, err := instanceDir()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(filepath.Join(dir, "go-home.lock"), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open lock file")
+	}
+
+	err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err == syscall.EWOULDBLOCK {
+		f.Close()
+		return nil, errAlreadyRunning
+	}
+	if err != nil {
+		f.Close()
+		return nil, errors.Wrap(err, "failed to lock instance")
+	}
+
+	return f, nil
+}
+
+// listen opens the control socket through which other invocations of go-home
+// can send commands to the running widget.
+func (app *App) listen() (net.Listener, error) {
+	path, err := socketPath()
+	if err != nil {
+		return nil, err
+	}
+
+	l, err := listenUnix(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open control socket")
+	}
+
+	go app.serve(l)
+	return l, nil
+}
+
+// listenUnix opens a unix socket at the given path. Since the widget holds the
+// instance lock, an existing socket is a leftover of a widget that was not shut
+// down properly and is removed. Anything else at the path is most likely a
+// typo and must not be deleted.
+func listenUnix(path string) (net.Listener, error) {
+	info, err := os.Lstat(path)
+	switch {
+	case err == nil && info.Mode()&os.ModeSocket == 0:
+		return nil, errors.Errorf("%s exists and is not a socket", path)
+	case err == nil:
+		os.Remove(path)
+	case !os.IsNotExist(err):
+		return nil, err
+	}
+
+	return net.Listen("unix", path)
+}
+
+func (app *App) serve(l net.Listener) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return // listener was closed
+		}
+
+		go app.handleConn(conn)
+	}
+}
+
+// handleConn reads a single command with its arguments from the connection and
+// replies with "ok" or with an error message.
+func (app *App) handleConn(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return
+	}
+
+	args := strings.Fields(line)
+	app.logger.Debug("Received command", zap.Strings("command", args))
+
+	fn, ok := app.command(args)
+	if ok {
+		err = app.do(fn)
+	} else {
+		err = errors.Errorf("unknown command %q", strings.TrimSpace(line))
+	}
+
+	if err != nil {
+		fmt.Fprintln(conn, "error:", err)
+		return
+	}
+
+	fmt.Fprintln(conn, "ok")
+}
+
+// do executes fn in the run loop and waits for the result.
+func (app *App) do(fn func() error) error {
+	req := request{fn: fn, reply: make(chan error, 1)}
+	select {
+	case app.requests <- req:
+	case <-time.After(5 * time.Second):
+		return errors.New("timeout while waiting for application")
+	}
+
+	return <-req.reply
+}
+
+// handleRequests executes all pending requests without blocking.
+func (app *App) handleRequests() {
+	for {
+		select {
+		case req := <-app.requests:
+			req.reply <- req.fn()
+		default:
+			return
+		}
+	}
+}
+
+// controlCommands lists all commands understood by the control socket.
+var controlCommands = []string{"show", "hide", "reload", "checkout", "toggle-remaining"}
+
+// command returns the function which executes the given command line in the
+// run loop. Besides the controlCommands, the widget understands
+// "correct <name> [<arg>]" which is sent by the correction commands.
+func (app *App) command(args []string) (func() error, bool) {
+	if len(args) == 0 {
+		return nil, false
+	}
+
+	switch args[0] {
+	case "correct":
+		if len(args) < 2 || len(args) > 3 || corrections[args[1]] == nil {
+			return nil, false
+		}
+		correct, arg := corrections[args[1]], ""
+		if len(args) == 3 {
+			arg = args[2]
+		}
+		return func() error {
+			invalid, err := app.applyCorrection(func(conf *Config, now time.Time) error {
+				return correct(conf, arg, now)
+			})
+			if invalid != nil {
+				return invalid
+			}
+			return err
+		}, true
+	case "show":
+		// Starting go-home again in the morning also starts the new day.
+		return func() error {
+			app.wake(time.Now())
+			app.hidden = false
+			app.win.SetPos(app.conf.WindowPos)
+			return nil
+		}, true
+	case "hide":
+		// PixelGL does not allow to hide a window so we move it out of sight.
+		return func() error {
+			app.hidden = true
+			app.win.SetPos(pixel.V(-10*app.render.Width, -10*app.render.Height))
+			return nil
+		}, true
+	case "reload":
+		return app.reload, true
+	case "checkout":
+		return func() error {
+			app.conf.CheckedOut = time.Now().Round(time.Second)
+			app.shutdown = true
+			return nil
+		}, true
+	case "toggle-remaining":
+		return func() error {
+			app.conf.UI.ShowRemainingTime = !app.conf.UI.ShowRemainingTime
+			app.render.ShowRemainingTime = app.conf.UI.ShowRemainingTime
+			return app.conf.Save()
+		}, true
+	default:
+		return nil, false
+	}
+}
+
+// sendCommand sends a command to the running widget.
+func sendCommand(name string) error {
+	path, err := socketPath()
+	if err != nil {
+		return err
+	}
+
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err != nil {
+		return errNotRunning
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	fmt.Fprintln(conn, name)
+
+	reply, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return errors.Wrap(err, "failed to read reply")
+	}
+
+	reply = strings.TrimSpace(reply)
+	if reply != "ok" {
+		return errors.New(strings.TrimPrefix(reply, "error: "))
+	}
+
+	return nil
+}
+
+func (app *App) controlCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:       fmt.Sprintf("ctl {%s}", strings.Join(controlCommands, "|")),
+		Short:     "Send a command to the running widget",
+		Args:      cobra.ExactValidArgs(1),
+		ValidArgs: controlCommands,
+		RunE: func(_ *cobra.Command, args []string) error {
+			return sendCommand(args[0])
+		},
+	}
+}
+
+// reload reads the configuration file again, e.g. because another command
+// has changed it. The current window position is kept. If the file cannot be
+// loaded, the current configuration is not changed.
+func (app *App) reload() error {
+	app.logger.Info("Reloading configuration")
+	conf, err := app.readConfig(app.conf.path, app.conf.statePath, app.conf.Debug)
+	if err != nil {
+		return err
+	}
+
+	if conf.Flexitime.Enabled {
+		err = app.loadBalance(&conf)
+		if err != nil {
+			return err
+		}
+	}
+
+	if conf.isDifferentDay(conf.CheckIn, app.conf.CheckIn) {
+		// Loading the file has started a new day so we must make sure the
+		// previous one is not lost (e.g. after working past the day boundary).
+		// It is taken from memory since the state file may be up to a minute
+		// older (see updateLastSeen).
+		if err := app.record(app.conf.Exit(time.Now())); err != nil {
+			return err
+		}
+		conf.lastDay = nil
+		if !app.afterHours {
+			app.runDayEndHooks(app.conf.Exit(time.Now()))
+		}
+		app.afterHours = false
+		app.render.AfterHours = false
+	}
+
+	if conf.Notifications.Enabled && app.notifier == nil {
+		app.enableNotifications()
+	}
+
+	conf.WindowPos = app.conf.WindowPos
+	if conf.UI.WindowWidth != app.conf.UI.WindowWidth || conf.UI.WindowHeight != app.conf.UI.WindowHeight {
+		app.win.SetBounds(pixel.R(0, 0, float64(conf.UI.WindowWidth), float64(conf.UI.WindowHeight)))
+	}
+
+	app.conf = conf
+	app.render.Update(app.conf)
+	return app.commitDay()
+}
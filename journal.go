@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// A Journal is an append-only log of work days. Each line of the underlying
+// file is a JSON encoded Day. The same date may appear multiple times (e.g.
+// once when the day started and once when the application was closed), in
+// which case later entries update the earlier ones.
+type Journal struct {
+	path string
+}
+
+// Day is a single entry in the Journal.
+type Day struct {
+	Date     string     `json:"date"` // formatted as "2006-01-02"
+	CheckIn  time.Time  `json:"check_in"`
+	CheckOut time.Time  `json:"check_out"`
+	Exit     *time.Time `json:"exit,omitempty"` // nil until the day is over
+	Breaks   Duration   `json:"breaks"`
+	Overtime Duration   `json:"overtime"`
+}
+
+// incomplete returns true if the day is over but has no exit time, e.g.
+// because Go Home crashed or was killed on logout.
+func (d Day) incomplete(today string) bool {
+	return d.Exit == nil && d.Date < today
+}
+
+// exitTime returns the exit time or the zero time if the day is not over yet.
+func (d Day) exitTime() time.Time {
+	if d.Exit == nil {
+		return time.Time{}
+	}
+
+	return *d.Exit
+}
+
+// Duration is a time.Duration which is encoded in JSON as human readable
+// string (e.g. "1h30m0s") instead of nanoseconds.
+type Duration time.Duration
+
+func NewJournal(path string) *Journal {
+	return &Journal{path: path}
+}
+
+// defaultJournalPath returns $XDG_STATE_HOME/go-home/log.jsonl.
+func defaultJournalPath() string {
+	return filepath.Join(xdgDir("XDG_STATE_HOME", ".local/state"), "go-home", "log.jsonl")
+}
+
+// Day returns the journal entry of the current configuration. The exit time
+// may be zero if the day is not over yet.
+func (conf Config) Day(exit time.Time) Day {
+	d := Day{
+		Date:     conf.WorkDate(conf.CheckIn).Format("2006-01-02"),
+		CheckIn:  conf.CheckIn,
+		CheckOut: conf.regularCheckOut(time.Now()),
+	}
+
+	if !exit.IsZero() {
+		d.CheckOut = conf.regularCheckOut(exit)
+		exit = exit.Round(time.Second)
+		d.Exit = &exit
+		d.Breaks = Duration(conf.BreakTime(exit))
+		d.Overtime = Duration(exit.Sub(d.CheckOut))
+	}
+
+	return d
+}
+
+// finishDay remembers the day in the state as lastDay before loading starts a
+// new day over it. Its exit is the manual check-out or otherwise the last time
+// the widget was seen running, e.g. if it was killed or the X server was gone.
+// If neither is known, the journal entry from the start of the day is kept.
+func (conf *Config) finishDay() {
+	exit := conf.CheckedOut
+	if exit.IsZero() {
+		exit = conf.LastSeen
+	}
+	if conf.CheckIn.IsZero() || exit.IsZero() {
+		return
+	}
+
+	conf.updateDay(exit)
+	if conf.Today.Holiday != "" {
+		return
+	}
+
+	d := conf.Day(exit)
+	conf.lastDay = &d
+}
+
+// record appends the current day to the journal. Holidays are not recorded.
+func (app *App) record(exit time.Time) error {
+	if app.conf.Today.Holiday != "" {
+		return nil
+	}
+
+	return app.journal.Append(app.conf.Day(exit))
+}
+
+// Append adds a new entry to the end of the journal file. The file and its
+// parent directory are created if they do not exist yet.
+func (j *Journal) Append(d Day) error {
+	err := os.MkdirAll(filepath.Dir(j.path), 0755)
+	if err != nil {
+		return errors.Wrap(err, "failed to create journal directory")
+	}
+
+	f, err := os.OpenFile(j.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return errors.Wrap(err, "failed to open journal")
+	}
+
+	err = json.NewEncoder(f).Encode(d)
+	if err != nil {
+		f.Close()
+		return errors.Wrap(err, "failed to write journal entry")
+	}
+
+	return errors.Wrap(f.Close(), "failed to close journal")
+}
+
+// Days reads all entries from the journal and returns them sorted by date.
+// Multiple entries for the same date are merged into a single Day.
+func (j *Journal) Days() ([]Day, error) {
+	f, err := os.Open(j.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open journal")
+	}
+	defer f.Close()
+
+	days := map[string]Day{}
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		d, err := decodeDay(scanner.Bytes())
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to decode journal entry in line %d", line)
+		}
+
+		days[d.Date] = days[d.Date].merge(d)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read journal")
+	}
+
+	result := make([]Day, 0, len(days))
+	for _, d := range days {
+		result = append(result, d)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Date < result[j].Date
+	})
+
+	return result, nil
+}
+
+// Balance returns the sum of the overtime of all days before the given date
+// (formatted as "2006-01-02"). Holidays of the calendar are ignored. Days
+// without exit time (e.g. after a crash) have no known overtime and do not
+// count either. Their dates are returned so they can be reported.
+func (j *Journal) Balance(before string, cal Calendar) (balance time.Duration, incomplete []string, err error) {
+	days, err := j.Days()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	for _, d := range days {
+		if _, off := cal.DayOff(d.Date); off || d.Date >= before {
+			continue
+		}
+		if d.Exit == nil {
+			incomplete = append(incomplete, d.Date)
+			continue
+		}
+		balance += time.Duration(d.Overtime)
+	}
+
+	return balance, incomplete, nil
+}
+
+// decodeDay decodes a single journal entry. Earlier versions wrote the zero
+// time as exit of days which were not over yet.
+func decodeDay(data []byte) (Day, error) {
+	var d Day
+	err := json.Unmarshal(data, &d)
+	if d.Exit != nil && d.Exit.IsZero() {
+		d.Exit = nil
+	}
+
+	return d, err
+}
+
+// merge updates d with all non-zero values of the newer entry.
+func (d Day) merge(newer Day) Day {
+	d.Date = newer.Date
+	if !newer.CheckIn.IsZero() {
+		d.CheckIn = newer.CheckIn
+	}
+	if !newer.CheckOut.IsZero() {
+		d.CheckOut = newer.CheckOut
+	}
+	if newer.Exit != nil {
+		d.Exit = newer.Exit
+		d.Breaks = newer.Breaks
+		d.Overtime = newer.Overtime
+	}
+
+	return d
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	x, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(x)
+	return nil
+}
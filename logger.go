@@ -20,8 +20,8 @@ func newLogger(debug bool) *zap.Logger {
 		Encoding:         "console",
 		EncoderConfig:    encConf,
 		DisableCaller:    true,
-		OutputPaths:      []string{"stdout"},
-		ErrorOutputPaths: []string{"stdout"},
+		OutputPaths:      []string{"stderr"}, // keep stdout free for the output of sub commands
+		ErrorOutputPaths: []string{"stderr"},
 	}
 
 	if debug {
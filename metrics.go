@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// writeMetrics writes the state of the current day in the Prometheus text
+// exposition format. All durations are exported in seconds and points in time
+// as Unix timestamps.
+func writeMetrics(w io.Writer, conf Config, now time.Time) {
+	// Only breaks which were actually taken count. The part of a fixed lunch
+	// break which is not covered by them is exported separately since we do
+	// not know when it is taken.
+	exit := conf.Exit(now)
+	breaks := conf.BreakDuration(exit)
+	worked := exit.Sub(conf.CheckIn) - breaks
+	if worked < 0 {
+		worked = 0
+	}
+
+	remaining := conf.CheckOut.Sub(now)
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	var overtime time.Duration
+	if exit.After(conf.CheckOut) {
+		overtime = exit.Sub(conf.CheckOut)
+	}
+
+	writeGauge(w, "go_home_worked_seconds", "Time worked today without breaks.", worked.Seconds())
+	writeGauge(w, "go_home_remaining_seconds", "Time until check-out.", remaining.Seconds())
+	writeGauge(w, "go_home_overtime_seconds", "Time worked after check-out.", overtime.Seconds())
+	writeGauge(w, "go_home_breaks", "Number of breaks taken today.", float64(len(conf.breaksUntil(now))))
+	writeGauge(w, "go_home_break_seconds", "Time spent in breaks today.", conf.BreakDuration(now).Seconds())
+	writeGauge(w, "go_home_lunch_seconds", "Part of the fixed lunch break which is not covered by the breaks taken today.", (conf.BreakTime(exit) - breaks).Seconds())
+	writeGauge(w, "go_home_check_in_timestamp_seconds", "Time of check-in.", unixSeconds(conf.CheckIn))
+	writeGauge(w, "go_home_check_out_timestamp_seconds", "Time at which it is time to go home.", unixSeconds(conf.CheckOut))
+	writeGauge(w, "go_home_end_of_day_timestamp_seconds", "Configured end of the day.", unixSeconds(conf.EndOfDay))
+	if conf.Flexitime.Enabled {
+		writeGauge(w, "go_home_flexitime_balance_seconds", "Flexitime balance of all previous days.", conf.Balance.Seconds())
+	}
+}
+
+func writeGauge(w io.Writer, name, help string, value float64) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s gauge\n", name)
+	fmt.Fprintf(w, "%s %g\n", name, value)
+}
+
+func unixSeconds(t time.Time) float64 {
+	return float64(t.UnixNano()) / float64(time.Second)
+}
+
+// GET /metrics exposes the current day for Prometheus.
+func (app *App) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	var buf bytes.Buffer
+	err := app.do(func() error {
+		writeMetrics(&buf, app.conf, time.Now())
+		return nil
+	})
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	buf.WriteTo(w)
+}
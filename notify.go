@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// NotificationConfig controls the desktop notifications which remind you to
+// go home. The messages are Go templates which are executed with the Status
+// of the current day (see the status command).
+type NotificationConfig struct {
+	Enabled       bool                 `yaml:"enabled"`
+	Before        []time.Duration      `yaml:"before,omitempty"` // notify when only this much time is left
+	AtCheckOut    bool                 `yaml:"at_check_out,omitempty"`
+	OvertimeEvery time.Duration        `yaml:"overtime_every,omitempty"` // repeat while working overtime
+	Messages      NotificationMessages `yaml:"messages,omitempty"`
+}
+
+// NotificationMessages contains the message templates of all notifications.
+// Empty messages fall back to defaultNotificationMessages.
+type NotificationMessages struct {
+	Before   string `yaml:"before,omitempty"`
+	CheckOut string `yaml:"check_out,omitempty"`
+	Overtime string `yaml:"overtime,omitempty"`
+}
+
+var defaultNotificationMessages = NotificationMessages{
+	Before:   "Check-out in {{ duration .Remaining }} at {{ clock .CheckOut }}",
+	CheckOut: "Time to go home!",
+	Overtime: "You are working {{ duration .Overtime }} overtime",
+}
+
+func (c NotificationConfig) check(ch *configChecker) {
+	for i, b := range c.Before {
+		if b <= 0 {
+			ch.add(fmt.Sprintf("notifications.before.%d", i), "notification offset %s must be positive", b)
+		}
+	}
+
+	ch.notNegative("notifications.overtime_every", c.OvertimeEvery)
+
+	messages := map[string]string{
+		"before":    c.Messages.Before,
+		"check_out": c.Messages.CheckOut,
+		"overtime":  c.Messages.Overtime,
+	}
+	for _, name := range []string{"before", "check_out", "overtime"} {
+		_, err := template.New("notification").Funcs(statusFuncs).Parse(messages[name])
+		if err != nil {
+			ch.add("notifications.messages."+name, "invalid message template: %v", err)
+		}
+	}
+}
+
+// due returns the message template of the latest notification which became
+// due after since and until now. The boolean is false if there is none.
+func (c NotificationConfig) due(checkOut, since, now time.Time) (msg string, at time.Time, ok bool) {
+	consider := func(t time.Time, m, fallback string) {
+		if !crossed(t, since, now) || (ok && !t.After(at)) {
+			return
+		}
+		if m == "" {
+			m = fallback
+		}
+		msg, at, ok = m, t, true
+	}
+
+	for _, b := range c.Before {
+		consider(checkOut.Add(-b), c.Messages.Before, defaultNotificationMessages.Before)
+	}
+
+	if c.AtCheckOut {
+		consider(checkOut, c.Messages.CheckOut, defaultNotificationMessages.CheckOut)
+	}
+
+	if c.OvertimeEvery > 0 && now.After(checkOut) {
+		n := now.Sub(checkOut) / c.OvertimeEvery
+		if n > 0 {
+			consider(checkOut.Add(n*c.OvertimeEvery), c.Messages.Overtime, defaultNotificationMessages.Overtime)
+		}
+	}
+
+	return msg, at, ok
+}
+
+// A Notifier sends desktop notifications via the org.freedesktop.Notifications
+// D-Bus interface.
+type Notifier struct {
+	conn *dbus.Conn
+}
+
+// NewNotifier creates a Notifier which uses the given connection. Usually this
+// is the session bus but tests may also pass a connection to a private bus.
+func NewNotifier(conn *dbus.Conn) *Notifier {
+	return &Notifier{conn: conn}
+}
+
+// Notify shows a notification with the given summary and body.
+func (n *Notifier) Notify(summary, body string) error {
+	obj := n.conn.Object("org.freedesktop.Notifications", "/org/freedesktop/Notifications")
+	call := obj.Call("org.freedesktop.Notifications.Notify", 0,
+		"go-home",                 // app_name
+		uint32(0),                 // replaces_id
+		"",                        // app_icon
+		summary,                   // summary
+		body,                      // body
+		[]string{},                // actions
+		map[string]dbus.Variant{}, // hints
+		int32(-1),                 // expire_timeout (server default)
+	)
+
+	return errors.Wrap(call.Err, "failed to send notification")
+}
+
+// Close closes the D-Bus connection.
+func (n *Notifier) Close() error {
+	return n.conn.Close()
+}
+
+// enableNotifications connects to the session bus. Failing to do so is not
+// fatal since the widget itself still shows when it is time to go home.
+func (app *App) enableNotifications() {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		app.logger.Warn("Desktop notifications are not available", zap.Error(err))
+		return
+	}
+
+	app.notifier = NewNotifier(conn)
+}
+
+// handleNotifications sends the notification which became due between the
+// previous frame and now. If multiple notifications are due at once (e.g.
+// after the computer was suspended), only the latest one is sent.
+func (app *App) handleNotifications(since, now time.Time) {
+	switch {
+	case app.notifier == nil, !app.conf.Notifications.Enabled:
+		return
+	case app.afterHours, app.conf.Today.Holiday != "", !app.conf.CheckedOut.IsZero():
+		return
+	}
+
+	msg, at, ok := app.conf.Notifications.due(app.conf.CheckOut, since, now)
+	if !ok {
+		return
+	}
+
+	tmpl, err := template.New("notification").Funcs(statusFuncs).Parse(msg)
+	if err != nil {
+		app.logger.Error("Invalid notification message", zap.Error(err))
+		return
+	}
+
+	var body bytes.Buffer
+	err = tmpl.Execute(&body, NewStatus(app.conf, at))
+	if err != nil {
+		app.logger.Error("Failed to render notification message", zap.Error(err))
+		return
+	}
+
+	app.logger.Debug("Sending notification", zap.String("body", body.String()))
+
+	// Sending the notification must not block the run loop.
+	n := app.notifier
+	go func() {
+		if err := n.Notify("Go Home", body.String()); err != nil {
+			app.logger.Warn("Failed to show desktop notification", zap.Error(err))
+		}
+	}()
+}
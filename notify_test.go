@@ -0,0 +1,266 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+func TestNotificationConfigDue(t *testing.T) {
+	at := func(clock string) time.Time {
+		c, err := time.Parse("15:04:05"[:len(clock)], clock)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return time.Date(2019, 6, 17, c.Hour(), c.Minute(), c.Second(), 0, time.UTC)
+	}
+
+	checkOut := at("17:00")
+
+	conf := NotificationConfig{
+		Enabled:       true,
+		Before:        []time.Duration{15 * time.Minute, 5 * time.Minute},
+		AtCheckOut:    true,
+		OvertimeEvery: 30 * time.Minute,
+		Messages:      NotificationMessages{Overtime: "overtime"},
+	}
+
+	tests := []struct {
+		name       string
+		conf       NotificationConfig
+		since, now time.Time
+		msg        string // empty if no notification is due
+		at         time.Time
+	}{
+		{
+			name:  "nothing due",
+			conf:  conf,
+			since: at("16:00"), now: at("16:01"),
+		},
+		{
+			name:  "first before threshold",
+			conf:  conf,
+			since: at("16:44"), now: at("16:45"),
+			msg: defaultNotificationMessages.Before, at: at("16:45"),
+		},
+		{
+			name:  "threshold was crossed during the previous frame",
+			conf:  conf,
+			since: at("16:45"), now: at("16:46"),
+		},
+		{
+			name:  "second before threshold",
+			conf:  conf,
+			since: at("16:54"), now: at("16:55:30"),
+			msg: defaultNotificationMessages.Before, at: at("16:55"),
+		},
+		{
+			name:  "at check-out",
+			conf:  conf,
+			since: at("16:59"), now: at("17:00"),
+			msg: defaultNotificationMessages.CheckOut, at: at("17:00"),
+		},
+		{
+			name:  "check-out is only due once",
+			conf:  conf,
+			since: at("17:00"), now: at("17:01"),
+		},
+		{
+			name:  "first overtime interval",
+			conf:  conf,
+			since: at("17:29"), now: at("17:30"),
+			msg: "overtime", at: at("17:30"),
+		},
+		{
+			name:  "later overtime interval",
+			conf:  conf,
+			since: at("18:29:59"), now: at("18:30:01"),
+			msg: "overtime", at: at("18:30"),
+		},
+		{
+			name:  "between overtime intervals",
+			conf:  conf,
+			since: at("17:31"), now: at("17:59"),
+		},
+		{
+			name:  "only the latest of several crossed thresholds",
+			conf:  conf,
+			since: at("16:00"), now: at("17:10"),
+			msg: defaultNotificationMessages.CheckOut, at: at("17:00"),
+		},
+		{
+			name:  "suspended until overtime",
+			conf:  conf,
+			since: at("16:00"), now: at("18:10"),
+			msg: "overtime", at: at("18:00"),
+		},
+		{
+			name:  "check-out disabled",
+			conf:  NotificationConfig{Before: []time.Duration{15 * time.Minute}},
+			since: at("16:59"), now: at("17:00"),
+		},
+		{
+			name:  "overtime disabled",
+			conf:  NotificationConfig{AtCheckOut: true},
+			since: at("17:29"), now: at("17:30"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, at, ok := tt.conf.due(checkOut, tt.since, tt.now)
+			if ok != (tt.msg != "") {
+				t.Fatalf("due() = %q, %v, %v, want %q", msg, at.Format("15:04:05"), ok, tt.msg)
+			}
+			if msg != tt.msg || !at.Equal(tt.at) {
+				t.Errorf("due() = %q at %s, want %q at %s", msg, at.Format("15:04:05"), tt.msg, tt.at.Format("15:04:05"))
+			}
+		})
+	}
+}
+
+// fakeNotifications implements the org.freedesktop.Notifications interface
+// and records all notifications it receives.
+type fakeNotifications struct {
+	mu    sync.Mutex
+	calls []fakeNotification
+}
+
+type fakeNotification struct {
+	AppName, Summary, Body string
+	Timeout                int32
+}
+
+func (f *fakeNotifications) Notify(appName string, replacesID uint32, icon, summary, body string,
+	actions []string, hints map[string]dbus.Variant, timeout int32) (uint32, *dbus.Error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls = append(f.calls, fakeNotification{AppName: appName, Summary: summary, Body: body, Timeout: timeout})
+	return uint32(len(f.calls)), nil
+}
+
+// startPrivateBus starts a dbus-daemon which is only used by the test and
+// returns its address and a function to stop it. The test is skipped if
+// dbus-daemon is not installed.
+func startPrivateBus(t *testing.T) (addr string, stop func()) {
+	daemon, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		t.Skip("dbus-daemon is not installed")
+	}
+
+	dir, err := ioutil.TempDir("", "go-home-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := filepath.Join(dir, "bus.conf")
+	err = ioutil.WriteFile(config, []byte(fmt.Sprintf(`<busconfig>
+  <type>session</type>
+  <listen>unix:dir=%s</listen>
+  <auth>EXTERNAL</auth>
+  <policy context="default">
+    <allow send_destination="*"/>
+    <allow receive_sender="*"/>
+    <allow own="*"/>
+  </policy>
+</busconfig>`, dir)), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(daemon, "--config-file="+config, "--nofork", "--print-address=1")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stop = func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+		os.RemoveAll(dir)
+	}
+
+	addr, err = bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		stop()
+		t.Fatal(err)
+	}
+
+	return strings.TrimSpace(addr), stop
+}
+
+func TestNotifier(t *testing.T) {
+	addr, stop := startPrivateBus(t)
+	defer stop()
+
+	server, err := dbus.Connect(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	fake := new(fakeNotifications)
+	err = server.Export(fake, "/org/freedesktop/Notifications", "org.freedesktop.Notifications")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reply, err := server.RequestName("org.freedesktop.Notifications", dbus.NameFlagDoNotQueue)
+	if err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+		t.Fatalf("failed to own name: %v (reply %d)", err, reply)
+	}
+
+	conn, err := dbus.Connect(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := NewNotifier(conn)
+	defer n.Close()
+
+	err = n.Notify("Go Home", "Time to go home!")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+
+	want := []fakeNotification{{AppName: "go-home", Summary: "Go Home", Body: "Time to go home!", Timeout: -1}}
+	if fmt.Sprint(fake.calls) != fmt.Sprint(want) {
+		t.Errorf("received notifications %+v, want %+v", fake.calls, want)
+	}
+}
+
+func TestNotifierWithoutDaemon(t *testing.T) {
+	addr, stop := startPrivateBus(t)
+	defer stop()
+
+	conn, err := dbus.Connect(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := NewNotifier(conn)
+	defer n.Close()
+
+	err = n.Notify("Go Home", "Time to go home!")
+	if err == nil {
+		t.Error("Notify() did not fail without a notification daemon")
+	}
+}
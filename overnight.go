@@ -0,0 +1,98 @@
+package main
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// handleNewDawn closes the current day when the widget is still running after
+// the day boundary (midnight by default). Depending on the overnight mode, the
+// widget waits for the first activity to start a new day, switches into after
+// hours mode or the work keeps counting towards the previous day.
+func (app *App) handleNewDawn(now time.Time) {
+	if app.afterHours || !app.conf.isDifferentDay(app.conf.CheckIn, now) {
+		return
+	}
+
+	if app.conf.Overnight == OvernightPreviousDay {
+		return
+	}
+
+	if app.conf.CheckedOut.IsZero() {
+		app.conf.CheckedOut = app.conf.EndOfWorkDay()
+
+		// If the screen is still locked, the day already ended when it was
+		// locked.
+		for _, b := range app.conf.Breaks {
+			if b.End.IsZero() && b.Start.Before(app.conf.CheckedOut) {
+				app.conf.CheckedOut = b.Start
+			}
+		}
+	}
+
+	err := app.record(app.conf.CheckedOut)
+	if err != nil {
+		app.logger.Error("Failed to record previous day", zap.Error(err))
+	}
+	app.runDayEndHooks(app.conf.CheckedOut)
+
+	app.afterHours = true
+	app.render.AfterHours = true
+	app.save()
+
+	if app.conf.Overnight == OvernightAfterHours {
+		app.logger.Info("Finished day at day boundary. Switching to after hours mode")
+	} else {
+		app.logger.Info("Finished day at day boundary. Waiting for activity to start a new day")
+	}
+}
+
+// continuesDay returns true if the day in the state is still counted by a
+// running widget in previous_day mode although the day boundary has passed.
+// The widget saves the state every minute while it is running, so reloading
+// the file and other commands like status must not start a new day then.
+func (conf Config) continuesDay(now time.Time) bool {
+	return conf.Overnight == OvernightPreviousDay && conf.CheckedOut.IsZero() &&
+		!conf.LastSeen.IsZero() && now.Sub(conf.LastSeen) < 2*time.Minute
+}
+
+// wake starts a new day if the widget is waiting for the first activity after
+// the day boundary (see OvernightNewDay). Activities are unlocking the screen,
+// input after an idle period, clicking the widget and corrections.
+func (app *App) wake(at time.Time) {
+	if !app.afterHours || app.conf.Overnight != OvernightNewDay {
+		return
+	}
+
+	if boundary := app.conf.EndOfWorkDay(); at.Before(boundary) {
+		at = boundary // e.g. the screen was unlocked just before the boundary
+	}
+
+	app.afterHours = false
+	app.render.AfterHours = false
+	app.conf.StartDay(at)
+	app.logger.Info("Detected start of new day", zap.String("date", app.conf.WorkDate(app.conf.CheckIn).Format("2006-01-02")))
+	if app.conf.Flexitime.Enabled {
+		// the balance now includes the day that just ended
+		if err := app.loadBalance(&app.conf); err != nil {
+			app.logger.Error("Failed to update flexitime balance", zap.Error(err))
+		}
+	}
+
+	app.render.Update(app.conf)
+	app.save()
+
+	if err := app.record(time.Time{}); err != nil {
+		app.logger.Error("Failed to record new day", zap.Error(err))
+	}
+	app.runHooks(HookDayStart, at)
+}
+
+// save writes the state and logs any error since there is nothing else we
+// can do about it in the run loop.
+func (app *App) save() {
+	if err := app.conf.SaveState(); err != nil {
+		app.logger.Error("Failed to save state", zap.Error(err))
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// configOverride is the value of a setting which was given as flag or
+// environment variable. Overrides take precedence over the settings file but
+// are never written back to it.
+type configOverride struct {
+	Path   string // e.g. "ui.width"
+	Value  string // parsed like a value in the settings file
+	Source string // e.g. "--width" or "GO_HOME_WIDTH"
+}
+
+// overridableSetting is a setting which can be overridden by a flag and an
+// environment variable.
+type overridableSetting struct {
+	Path string       // e.g. "ui.show_remaining_time"
+	Flag string       // e.g. "show-remaining-time"
+	Env  string       // e.g. "GO_HOME_SHOW_REMAINING_TIME"
+	Type reflect.Type // type of the Config field
+}
+
+// overridableSettings returns all settings with a single value. Lists and the
+// weekly schedule can only be set in the settings file. Flags of the ui
+// section do not have a prefix (e.g. --width).
+func overridableSettings() []overridableSetting {
+	var settings []overridableSetting
+	var walk func(path string, t reflect.Type)
+	walk = func(path string, t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name := strings.Split(f.Tag.Get("yaml"), ",")[0]
+			if f.PkgPath != "" || name == "-" || name == "" || name == "version" {
+				continue
+			}
+
+			ptr := reflect.PtrTo(f.Type)
+			custom := ptr.Implements(yamlUnmarshalerType) || ptr.Implements(textUnmarshalerType)
+			switch {
+			case f.Type.Kind() == reflect.Struct && !custom:
+				walk(joinPath(path, name), f.Type)
+			case f.Type.Kind() == reflect.Slice, f.Type.Kind() == reflect.Ptr, f.Type.Kind() == reflect.Map:
+				// not a single value
+			default:
+				flag := strings.TrimPrefix(joinPath(path, name), "ui.")
+				flag = strings.NewReplacer(".", "-", "_", "-").Replace(flag)
+				settings = append(settings, overridableSetting{
+					Path: joinPath(path, name),
+					Flag: flag,
+					Env:  "GO_HOME_" + strings.ToUpper(strings.Replace(flag, "-", "_", -1)),
+					Type: f.Type,
+				})
+			}
+		}
+	}
+
+	walk("", reflect.TypeOf(Config{}))
+	return settings
+}
+
+// overrideFlag records the raw value of a flag. It is decoded together with
+// the settings file so flags accept the same values.
+type overrideFlag struct {
+	value string
+	typ   string
+}
+
+func (f *overrideFlag) String() string     { return f.value }
+func (f *overrideFlag) Set(s string) error { f.value = s; return nil }
+func (f *overrideFlag) Type() string       { return f.typ }
+
+// addOverrideFlags adds a persistent flag for each overridable setting.
+func addOverrideFlags(cmd *cobra.Command) {
+	flags := cmd.PersistentFlags()
+	for _, s := range overridableSettings() {
+		typ := s.Type.Kind().String()
+		switch s.Type {
+		case reflect.TypeOf(time.Duration(0)):
+			typ = "duration"
+		case reflect.TypeOf(ClockTime{}):
+			typ = "hh:mm"
+		}
+
+		flags.Var(&overrideFlag{typ: typ}, s.Flag, "override the "+s.Path+" setting (env "+s.Env+")")
+		if s.Type.Kind() == reflect.Bool {
+			flags.Lookup(s.Flag).NoOptDefVal = "true"
+		}
+	}
+}
+
+// overrides returns the settings which were given as flag or environment
+// variable. Flags take precedence over environment variables.
+func (app *App) overrides() []configOverride {
+	var overrides []configOverride
+	for _, s := range overridableSettings() {
+		if f := app.PersistentFlags().Lookup(s.Flag); f != nil && f.Changed {
+			overrides = append(overrides, configOverride{Path: s.Path, Value: f.Value.String(), Source: "--" + s.Flag})
+		} else if v := os.Getenv(s.Env); v != "" {
+			overrides = append(overrides, configOverride{Path: s.Path, Value: v, Source: s.Env})
+		}
+	}
+
+	return overrides
+}
+
+// applyOverrides decodes the overrides into conf. Invalid values are reported
+// to the checker.
+func (conf *Config) applyOverrides(overrides []configOverride, checker *configChecker) error {
+	for _, o := range overrides {
+		// Problems of the overridden value must not point to the line of the
+		// value in the settings file.
+		deleteYAMLPath(checker.root, o.Path)
+
+		root := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		err := setYAMLPath(root, o.Path, &yaml.Node{Kind: yaml.ScalarNode, Value: o.Value})
+		if err != nil {
+			return err
+		}
+
+		c := new(configChecker)
+		c.checkNode("", root, reflect.TypeOf(*conf), true)
+		for _, p := range c.problems {
+			p.Path = o.Source
+			checker.problems = append(checker.problems, p)
+		}
+		if len(c.problems) > 0 {
+			continue
+		}
+
+		err = root.Decode(conf)
+		if err != nil {
+			return errors.Wrapf(err, "failed to decode %s", o.Source)
+		}
+	}
+
+	conf.overrides = overrides
+	return nil
+}
+
+// overriddenPaths returns the settings which must not be saved because their
+// value was given as flag or environment variable.
+func (conf Config) overriddenPaths() []string {
+	paths := make([]string, len(conf.overrides))
+	for i, o := range conf.overrides {
+		paths[i] = o.Path
+	}
+
+	return paths
+}
@@ -20,10 +20,15 @@ type Render struct {
 	CheckIn           time.Time
 	CheckOut          time.Time
 	EOD               time.Time
+	Breaks            []Break
+	DayOff            string // name of the holiday or empty on regular days
+	AfterHours        bool   // the day has ended at the day boundary
 	Atlas             *text.Atlas
 	MarkerColor       color.Color
 	BorderColor       color.Color
 	ShowRemainingTime bool          // show how much time is left instead of the current time
+	ShowBalance       bool          // show the flexitime balance at the right end of the bar
+	Balance           time.Duration // flexitime balance of all previous days
 	timeShift         time.Duration // for debugging
 }
 
@@ -33,16 +38,27 @@ func NewRender(conf Config) (*Render, error) {
 		return nil, errors.Wrap(err, "failed to load font")
 	}
 
-	return &Render{
-		Width:             float64(conf.UI.WindowWidth),
-		Height:            float64(conf.UI.WindowHeight),
-		CheckIn:           conf.CheckIn,
-		CheckOut:          conf.CheckOut,
-		EOD:               conf.EndOfDay,
-		Atlas:             text.NewAtlas(fnt, text.ASCII),
-		MarkerColor:       colornames.Mediumblue,
-		ShowRemainingTime: conf.UI.ShowRemainingTime,
-	}, nil
+	r := &Render{
+		Atlas:       text.NewAtlas(fnt, text.ASCII),
+		MarkerColor: colornames.Mediumblue,
+	}
+
+	r.Update(conf)
+	return r, nil
+}
+
+// Update copies all values of the configuration which are relevant for drawing.
+func (r *Render) Update(conf Config) {
+	r.Width = float64(conf.UI.WindowWidth)
+	r.Height = float64(conf.UI.WindowHeight)
+	r.CheckIn = conf.CheckIn
+	r.CheckOut = conf.CheckOut
+	r.EOD = conf.EndOfDay
+	r.Breaks = conf.Breaks
+	r.DayOff = conf.Today.Holiday
+	r.ShowRemainingTime = conf.UI.ShowRemainingTime
+	r.ShowBalance = conf.Flexitime.Enabled
+	r.Balance = conf.Balance
 }
 
 func loadTTF(path string, size float64) (font.Face, error) {
@@ -59,13 +75,23 @@ func loadTTF(path string, size float64) (font.Face, error) {
 }
 
 func (r *Render) Draw(t pixel.Target) {
+	switch {
+	case r.DayOff != "":
+		r.drawMessage(t, "Day off: "+r.DayOff)
+		return
+	case r.AfterHours:
+		r.drawMessage(t, "After hours")
+		return
+	}
+
 	now := time.Now().Add(r.timeShift)
-	progress := r.progress(now)
+	progress := math.Min(r.progress(now), 1) // the day might continue after the day end
 
 	markerTxt := r.markerText(progress, now)
 	checkoutTxt := r.checkoutText(now)
 
 	r.drawGradient(t, progress)
+	r.drawBreaks(t, now)
 	r.drawCells(t, markerTxt, checkoutTxt)
 	r.drawTargetMarker(t, progress, markerTxt)
 	r.drawCurrentMarker(t, progress, now, markerTxt)
@@ -73,6 +99,24 @@ func (r *Render) Draw(t pixel.Target) {
 	r.drawRectangle(t, now)
 }
 
+// drawMessage replaces the progress bar with a message, e.g. on holidays.
+func (r *Render) drawMessage(t pixel.Target, msg string) {
+	rect := imdraw.New(nil)
+	rect.Color = pixel.RGB(0.333, 0.333, 0.333)
+	rect.Push(pixel.V(0, 0))
+	rect.Push(pixel.V(r.Width, r.Height))
+	rect.Rectangle(0)
+	rect.Draw(t)
+
+	txt := text.New(pixel.V(4, 4), r.Atlas)
+	txt.Color = color.White
+	txt.WriteString(msg)
+	shift := r.Height/2 - txt.Bounds().Size().Y/2 - 1
+	txt.Draw(t, pixel.IM.Moved(pixel.V(0, shift)))
+
+	r.drawBorder(t, color.Black, 2)
+}
+
 func (r *Render) drawGradient(t pixel.Target, progress float64) {
 	leftColor := pixel.RGB(0, 1, 0)
 	rightColor := pixel.RGB(progress, 1-progress, 0)
@@ -102,6 +146,28 @@ func (r *Render) drawGradient(t pixel.Target, progress float64) {
 	rect.Draw(t)
 }
 
+// drawBreaks draws a gap into the progress bar for each break until now.
+func (r *Render) drawBreaks(t pixel.Target, now time.Time) {
+	for _, b := range r.Breaks {
+		if b.Start.After(now) {
+			continue
+		}
+
+		end := b.End
+		if end.IsZero() || end.After(now) {
+			end = now
+		}
+
+		left, right := r.position(b.Start), r.position(end)
+		rect := imdraw.New(nil)
+		rect.Color = pixel.RGB(0.9, 0.9, 0.9).Mul(pixel.Alpha(0.8))
+		rect.Push(pixel.V(left, 2))
+		rect.Push(pixel.V(right, r.Height-2))
+		rect.Rectangle(0)
+		rect.Draw(t)
+	}
+}
+
 func (r *Render) drawCells(t pixel.Target, markerTxt, checkoutTxt *text.Text) {
 	numCells := 9
 	txtBounds := markerTxt.Bounds()
@@ -209,8 +275,6 @@ func (r *Render) checkoutText(now time.Time) *text.Text {
 
 func (r *Render) drawRectangle(t pixel.Target, now time.Time) {
 	var borderWidth float64 = 2
-
-	rect := imdraw.New(nil)
 	if now.Before(r.CheckOut) {
 		r.BorderColor = color.Black
 	} else {
@@ -221,14 +285,19 @@ func (r *Render) drawRectangle(t pixel.Target, now time.Time) {
 		borderWidth = 4
 	}
 
-	rect.Color = r.BorderColor
+	r.drawBorder(t, r.BorderColor, borderWidth)
+}
+
+func (r *Render) drawBorder(t pixel.Target, col color.Color, width float64) {
+	rect := imdraw.New(nil)
+	rect.Color = col
 	rect.EndShape = imdraw.RoundEndShape
 	rect.Push(pixel.V(1, 1))
 	rect.Push(pixel.V(1, r.Height-1))
 	rect.Push(pixel.V(r.Width-1, r.Height-1))
 	rect.Push(pixel.V(r.Width-1, 1))
 	rect.Push(pixel.V(1, 1))
-	rect.Line(borderWidth)
+	rect.Line(width)
 	rect.Draw(t)
 }
 
@@ -249,16 +318,51 @@ func (r *Render) drawText(t pixel.Target, now time.Time, markerTxt, checkoutTxt
 		checkoutTxt.Draw(t, m)
 	}
 
+	if r.ShowBalance {
+		balanceTxt := r.balanceText()
+		if balanceTxt.Bounds().Intersect(markerTxt.Bounds()) == pixel.ZR &&
+			balanceTxt.Bounds().Intersect(checkoutTxt.Bounds()) == pixel.ZR {
+			balanceTxt.Draw(t, m)
+		}
+	}
+
 	markerTxt.Draw(t, m)
 }
 
+// balanceText returns the flexitime balance right aligned at the end of the
+// bar (e.g. "+1:30").
+func (r *Render) balanceText() *text.Text {
+	s := formatDuration(r.Balance)
+	if r.Balance >= 0 {
+		s = "+" + s
+	}
+
+	txt := text.New(pixel.ZV, r.Atlas)
+	width := txt.BoundsOf(s).W()
+	txt.Orig = pixel.V(r.Width-width-6, 4)
+	txt.Dot = txt.Orig
+	txt.Color = color.White
+	txt.WriteString(s)
+	return txt
+}
+
 func (r *Render) position(t time.Time) float64 {
 	return r.Width * r.progress(t)
 }
 
 func (r *Render) progress(now time.Time) float64 {
-	left := float64(r.CheckIn.Unix())
-	right := float64(r.EOD.Unix())
+	return progress(r.CheckIn, r.EOD, now)
+}
+
+// progress returns the relative position of now between the start and end of
+// the day (i.e. 0 at the start of the day and 1 at its end).
+func progress(start, end, now time.Time) float64 {
+	if !end.After(start) {
+		return 1 // e.g. checked in after the configured day end
+	}
+
+	left := float64(start.Unix())
+	right := float64(end.Unix())
 	nowUnix := float64(now.Unix())
 
 	totalSec := right - left
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+type reportOptions struct {
+	week, month bool
+	from, to    string
+	output      string
+}
+
+// reportEntry is a Day of the journal including the derived work time.
+type reportEntry struct {
+	Day
+	Worked     Duration `json:"worked"`
+	Incomplete bool     `json:"incomplete,omitempty"` // the day is over but has no exit time
+}
+
+func (app *App) reportCommand() *cobra.Command {
+	var opts reportOptions
+	cmd := &cobra.Command{
+		Use:   "report",
+		Short: "Print a timesheet of the recorded work days",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if app.initErr != nil {
+				return app.initErr
+			}
+
+			return app.report(cmd.OutOrStdout(), opts, app.conf.WorkDate(time.Now()))
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.BoolVar(&opts.week, "week", false, "report the current week (default)")
+	flags.BoolVar(&opts.month, "month", false, "report the current month")
+	flags.StringVar(&opts.from, "from", "", "first day of the report (YYYY-MM-DD)")
+	flags.StringVar(&opts.to, "to", "", "last day of the report (YYYY-MM-DD)")
+	flags.StringVarP(&opts.output, "output", "o", "table", "output format (table, csv or json)")
+
+	return cmd
+}
+
+func (app *App) report(w io.Writer, opts reportOptions, now time.Time) error {
+	from, to, err := opts.dateRange(now)
+	if err != nil {
+		return err
+	}
+
+	days, err := app.journal.Days()
+	if err != nil {
+		return errors.Wrap(err, "failed to read journal")
+	}
+
+	entries := reportEntries(days, app.conf.calendar, from, to, now)
+	switch opts.output {
+	case "table":
+		return writeReportTable(w, entries)
+	case "csv":
+		return writeReportCSV(w, entries)
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	default:
+		return errors.Errorf("unknown output format %q", opts.output)
+	}
+}
+
+// reportEntries returns all days between from and to (inclusive) which are
+// not holidays. Days before today without exit time are marked as incomplete.
+func reportEntries(days []Day, cal Calendar, from, to string, today time.Time) []reportEntry {
+	entries := []reportEntry{}
+	for _, d := range days {
+		if d.Date < from || d.Date > to {
+			continue
+		}
+		if _, off := cal.DayOff(d.Date); off {
+			continue
+		}
+		entries = append(entries, reportEntry{
+			Day:        d,
+			Worked:     Duration(d.Worked()),
+			Incomplete: d.incomplete(today.Format("2006-01-02")),
+		})
+	}
+
+	return entries
+}
+
+// dateRange returns the first and last date of the report formatted as
+// "2006-01-02" so they can be compared directly with Day.Date.
+func (opts reportOptions) dateRange(now time.Time) (from, to string, err error) {
+	const layout = "2006-01-02"
+	year, month, day := now.Date()
+	today := time.Date(year, month, day, 0, 0, 0, 0, now.Location())
+
+	switch {
+	case opts.from != "" || opts.to != "":
+		from, to = opts.from, opts.to
+		if from == "" {
+			from = "0000-01-01"
+		} else if _, err := time.Parse(layout, from); err != nil {
+			return "", "", errors.Errorf("invalid --from date %q: expected YYYY-MM-DD", from)
+		}
+		if to == "" {
+			to = today.Format(layout)
+		} else if _, err := time.Parse(layout, to); err != nil {
+			return "", "", errors.Errorf("invalid --to date %q: expected YYYY-MM-DD", to)
+		}
+		return from, to, nil
+	case opts.month:
+		first := time.Date(year, month, 1, 0, 0, 0, 0, now.Location())
+		last := first.AddDate(0, 1, -1)
+		return first.Format(layout), last.Format(layout), nil
+	default: // current week starting on Monday
+		offset := (int(today.Weekday()) + 6) % 7
+		monday := today.AddDate(0, 0, -offset)
+		return monday.Format(layout), monday.AddDate(0, 0, 6).Format(layout), nil
+	}
+}
+
+// Worked returns how much time was spent working on that day. It returns
+// zero if the day has no exit time yet.
+func (d Day) Worked() time.Duration {
+	if d.Exit == nil {
+		return 0
+	}
+
+	return d.Exit.Sub(d.CheckIn) - time.Duration(d.Breaks)
+}
+
+func writeReportTable(w io.Writer, entries []reportEntry) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "DATE\tCHECK-IN\tCHECK-OUT\tBREAKS\tWORKED\tOVERTIME")
+
+	var worked, overtime time.Duration
+	var incomplete bool
+	for _, e := range entries {
+		date := e.Date
+		if e.Incomplete {
+			date += "*"
+			incomplete = true
+		}
+
+		fmt.Fprintf(tw, "%s %s\t%s\t%s\t%s\t%s\t%s\n",
+			e.CheckIn.Format("Mon"), date,
+			formatClock(e.CheckIn),
+			formatClock(e.exitTime()),
+			e.formatDuration(e.Breaks),
+			e.formatDuration(e.Worked),
+			e.formatDuration(e.Overtime),
+		)
+		worked += time.Duration(e.Worked)
+		overtime += time.Duration(e.Overtime)
+	}
+
+	fmt.Fprintf(tw, "TOTAL\t\t\t\t%s\t%s\n", formatDuration(worked), formatDuration(overtime))
+	err := tw.Flush()
+	if err != nil || !incomplete {
+		return err
+	}
+
+	_, err = fmt.Fprintln(w, "\n* no exit time was recorded (e.g. after a crash), so the day is not counted")
+	return err
+}
+
+func writeReportCSV(w io.Writer, entries []reportEntry) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"date", "check_in", "check_out", "breaks", "worked", "overtime"})
+	for _, e := range entries {
+		cw.Write([]string{
+			e.Date,
+			formatClock(e.CheckIn),
+			formatClock(e.exitTime()),
+			e.formatDuration(e.Breaks),
+			e.formatDuration(e.Worked),
+			e.formatDuration(e.Overtime),
+		})
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// formatDuration formats a duration of the entry. Days without exit time are
+// not over yet so their durations are not known.
+func (e reportEntry) formatDuration(d Duration) string {
+	if e.Exit == nil {
+		return "-"
+	}
+
+	return formatDuration(time.Duration(d))
+}
+
+func formatClock(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+
+	return t.Format("15:04")
+}
+
+// formatDuration formats d as hours and minutes (e.g. "7:30" or "-0:15").
+func formatDuration(d time.Duration) string {
+	sign := ""
+	if d < 0 {
+		sign = "-"
+		d = -d
+	}
+
+	d = d.Round(time.Minute)
+	return fmt.Sprintf("%s%d:%02d", sign, d/time.Hour, (d%time.Hour)/time.Minute)
+}
@@ -0,0 +1,90 @@
+package main
+
+import "time"
+
+// A Schedule overrides the global work settings for individual days of the
+// week. Days which are not configured use the global settings.
+type Schedule struct {
+	Monday    *WorkDay `yaml:"monday,omitempty"`
+	Tuesday   *WorkDay `yaml:"tuesday,omitempty"`
+	Wednesday *WorkDay `yaml:"wednesday,omitempty"`
+	Thursday  *WorkDay `yaml:"thursday,omitempty"`
+	Friday    *WorkDay `yaml:"friday,omitempty"`
+	Saturday  *WorkDay `yaml:"saturday,omitempty"`
+	Sunday    *WorkDay `yaml:"sunday,omitempty"`
+}
+
+// WorkDay contains the work settings of a single day. Zero values fall back
+// to the global settings of the Config.
+type WorkDay struct {
+	WorkDuration  time.Duration `yaml:"work_duration,omitempty"`
+	LunchDuration time.Duration `yaml:"lunch_duration,omitempty"`
+	DayEnd        ClockTime     `yaml:"day_end,omitempty"`
+	Off           bool          `yaml:"off,omitempty"` // no work is expected on this day
+	Holiday       string        `yaml:"-"`             // name of the holiday if this is one
+}
+
+// For returns the configured settings of the given weekday or nil if there
+// are none.
+func (s Schedule) For(day time.Weekday) *WorkDay {
+	switch day {
+	case time.Monday:
+		return s.Monday
+	case time.Tuesday:
+		return s.Tuesday
+	case time.Wednesday:
+		return s.Wednesday
+	case time.Thursday:
+		return s.Thursday
+	case time.Friday:
+		return s.Friday
+	case time.Saturday:
+		return s.Saturday
+	case time.Sunday:
+		return s.Sunday
+	default:
+		return nil
+	}
+}
+
+// workDay returns the effective settings for the given work date (see
+// Config.WorkDate) by applying its schedule entry to the global settings. On
+// days off, all work counts as overtime. Holidays are days off which are not
+// recorded at all.
+func (conf Config) workDay(day time.Time) WorkDay {
+	wd := WorkDay{
+		WorkDuration:  conf.WorkDuration,
+		LunchDuration: conf.LunchDuration,
+		DayEnd:        conf.DayEnd,
+	}
+
+	if name, ok := conf.calendar.DayOff(day.Format("2006-01-02")); ok {
+		wd.Holiday = name
+		wd.Off = true
+		wd.WorkDuration = 0
+		wd.LunchDuration = 0
+		return wd
+	}
+
+	entry := conf.Schedule.For(day.Weekday())
+	if entry == nil {
+		return wd
+	}
+
+	if entry.WorkDuration != 0 {
+		wd.WorkDuration = entry.WorkDuration
+	}
+	if entry.LunchDuration != 0 {
+		wd.LunchDuration = entry.LunchDuration
+	}
+	if entry.DayEnd != (ClockTime{}) {
+		wd.DayEnd = entry.DayEnd
+	}
+	if entry.Off {
+		wd.Off = true
+		wd.WorkDuration = 0
+		wd.LunchDuration = 0
+	}
+
+	return wd
+}
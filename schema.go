@@ -0,0 +1,135 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// configVersion is the version of the settings file format. Increase it and
+// add a step to configMigrations whenever a setting is renamed, moved or
+// changes its meaning.
+const configVersion = 1
+
+// configMigrations convert the settings file from one version to the next.
+// The function at index i migrates a file of version i to version i+1. Files
+// which were written before the version was introduced have version 0. The
+// migrations work on the YAML document so comments are kept.
+var configMigrations = []func(root *yaml.Node){
+	// The state of the current day moved into its own file (see
+	// migrateLegacyConfig). Remove it in case an old file was copied to the
+	// new location by hand.
+	func(root *yaml.Node) {
+		for _, path := range []string{"check_in", "breaks", "checked_out", "ui.pos"} {
+			deleteYAMLPath(root, path)
+		}
+	},
+}
+
+// migrateConfig upgrades the settings document to configVersion and returns
+// the version of the file. Files of newer versions are left unchanged.
+func migrateConfig(root *yaml.Node) (int, error) {
+	var version int
+	if key, value := findYAMLPath(root, "version"); value != nil {
+		err := value.Decode(&version)
+		if err != nil || version < 0 {
+			return 0, errors.Errorf("line %d: invalid version %q", key.Line, value.Value)
+		}
+	}
+
+	for v := version; v < configVersion; v++ {
+		configMigrations[v](root)
+	}
+
+	return version, nil
+}
+
+// migrateSettings migrates the document of the settings file before it is
+// updated by Save, so settings which were removed by a migration do not stay
+// in the file. Errors are ignored since such a file cannot be loaded anyway.
+func migrateSettings(root *yaml.Node) {
+	migrateConfig(root)
+}
+
+// findYAMLPath returns the key and the value node of the setting at the given
+// dot separated path (e.g. "ui.width" or "notifications.before.0"). Items of
+// sequences are their own key. Both are nil if the setting does not exist.
+func findYAMLPath(n *yaml.Node, path string) (key, value *yaml.Node) {
+	if n == nil {
+		return nil, nil
+	}
+
+	key = n
+	for _, name := range strings.Split(path, ".") {
+		switch n.Kind {
+		case yaml.MappingNode:
+			i := yamlKeyIndex(n.Content, name)
+			if i < 0 {
+				return nil, nil
+			}
+			key, n = n.Content[i], n.Content[i+1]
+		case yaml.SequenceNode:
+			i, err := strconv.Atoi(name)
+			if err != nil || i < 0 || i >= len(n.Content) {
+				return nil, nil
+			}
+			key, n = n.Content[i], n.Content[i]
+		default:
+			return nil, nil
+		}
+	}
+
+	return key, n
+}
+
+// deleteYAMLPath removes the setting at the given path from its mapping.
+func deleteYAMLPath(root *yaml.Node, path string) {
+	parent := root
+	if i := strings.LastIndex(path, "."); i >= 0 {
+		_, parent = findYAMLPath(root, path[:i])
+		path = path[i+1:]
+	}
+	if parent == nil || parent.Kind != yaml.MappingNode {
+		return
+	}
+
+	if i := yamlKeyIndex(parent.Content, path); i >= 0 {
+		parent.Content = append(parent.Content[:i], parent.Content[i+2:]...)
+	}
+}
+
+// setYAMLPath sets the setting at the given path. Missing mappings on the way
+// are created. An existing value is updated in place so its comments are kept.
+func setYAMLPath(root *yaml.Node, path string, value *yaml.Node) error {
+	n := root
+	names := strings.Split(path, ".")
+	for i, name := range names {
+		if n.Kind != yaml.MappingNode {
+			return errors.Errorf("%s is not a mapping", strings.Join(names[:i], "."))
+		}
+		if len(n.Content) == 0 {
+			n.Style = 0 // do not write new keys into an empty {}
+		}
+
+		j := yamlKeyIndex(n.Content, name)
+		if j < 0 {
+			child := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			if i == len(names)-1 {
+				child = value
+			}
+			key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name}
+			n.Content = append(n.Content, key, child)
+			n = child
+			continue
+		}
+
+		if i == len(names)-1 {
+			mergeYAMLNode(n.Content[j+1], value)
+		}
+		n = n.Content[j+1]
+	}
+
+	return nil
+}
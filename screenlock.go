@@ -0,0 +1,195 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// The ScreenLockDetector listens for screen lock signals on the D-Bus and
+// reports the periods in which the screen was locked as breaks.
+type ScreenLockDetector struct {
+	logger  *zap.Logger
+	conns   []*dbus.Conn
+	signals chan *dbus.Signal
+	events  chan<- BreakEvent
+	done    chan struct{}
+}
+
+// screenSaverInterfaces are the D-Bus interfaces that emit an ActiveChanged
+// signal with a single boolean when the screen gets locked or unlocked.
+var screenSaverInterfaces = []string{
+	"org.gnome.ScreenSaver",
+	"org.freedesktop.ScreenSaver",
+}
+
+const logindSessionInterface = "org.freedesktop.login1.Session"
+
+// NewScreenLockDetector subscribes to lock and unlock signals on the given
+// connections. Usually those are the session bus (for the screen saver
+// signals) and the system bus (for the logind signals) but tests may also
+// pass connections to a private bus. All detected breaks are sent to the
+// events channel.
+func NewScreenLockDetector(events chan<- BreakEvent, logger *zap.Logger, conns ...*dbus.Conn) (*ScreenLockDetector, error) {
+	d := &ScreenLockDetector{
+		logger:  logger,
+		conns:   conns,
+		signals: make(chan *dbus.Signal, 10),
+		events:  events,
+		done:    make(chan struct{}),
+	}
+
+	for _, conn := range conns {
+		for _, iface := range screenSaverInterfaces {
+			err := conn.AddMatchSignal(
+				dbus.WithMatchInterface(iface),
+				dbus.WithMatchMember("ActiveChanged"),
+			)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to subscribe to %s signals", iface)
+			}
+		}
+
+		// Without the path of our session, the locks of all sessions would
+		// count as breaks, so logind is only used if it knows the session.
+		path, err := logindSessionPath(conn)
+		if err != nil {
+			logger.Debug("Ignoring logind signals since the session is unknown", zap.Error(err))
+		} else {
+			err = conn.AddMatchSignal(
+				dbus.WithMatchInterface(logindSessionInterface),
+				dbus.WithMatchObjectPath(path),
+			)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to subscribe to logind signals")
+			}
+		}
+
+		conn.Signal(d.signals)
+	}
+
+	go d.run()
+
+	return d, nil
+}
+
+// logindSessionPath returns the D-Bus object path of the logind session of
+// this process so we do not react to other users locking their sessions. If
+// the process is not part of a session, e.g. because it runs as systemd user
+// service, the session is looked up by $XDG_SESSION_ID.
+func logindSessionPath(conn *dbus.Conn) (dbus.ObjectPath, error) {
+	var path dbus.ObjectPath
+	obj := conn.Object("org.freedesktop.login1", "/org/freedesktop/login1")
+	err := obj.Call("org.freedesktop.login1.Manager.GetSessionByPID", 0, uint32(os.Getpid())).Store(&path)
+	if err == nil {
+		return path, nil
+	}
+
+	if id := os.Getenv("XDG_SESSION_ID"); id != "" {
+		err = obj.Call("org.freedesktop.login1.Manager.GetSession", 0, id).Store(&path)
+	}
+
+	return path, err
+}
+
+func (d *ScreenLockDetector) run() {
+	for {
+		select {
+		case sig := <-d.signals:
+			e, ok := screenLockEvent(sig)
+			if !ok {
+				continue
+			}
+
+			d.logger.Debug("Detected screen lock signal",
+				zap.String("signal", sig.Name),
+				zap.Bool("locked", e.Start),
+			)
+
+			select {
+			case d.events <- e:
+			case <-d.done:
+				return
+			}
+		case <-d.done:
+			return
+		}
+	}
+}
+
+// screenLockEvent converts a D-Bus signal into a BreakEvent. The boolean
+// return value is false if the signal is not related to screen locking.
+func screenLockEvent(sig *dbus.Signal) (BreakEvent, bool) {
+	e := BreakEvent{Source: "screen_lock", Time: time.Now()}
+	switch sig.Name {
+	case logindSessionInterface + ".Lock":
+		e.Start = true
+		return e, true
+	case logindSessionInterface + ".Unlock":
+		e.Start = false
+		return e, true
+	}
+
+	for _, iface := range screenSaverInterfaces {
+		if sig.Name != iface+".ActiveChanged" || len(sig.Body) != 1 {
+			continue
+		}
+
+		active, ok := sig.Body[0].(bool)
+		e.Start = active
+		return e, ok
+	}
+
+	return e, false
+}
+
+// Close stops listening for signals and closes all D-Bus connections.
+func (d *ScreenLockDetector) Close() error {
+	close(d.done)
+
+	var err error
+	for _, conn := range d.conns {
+		conn.RemoveSignal(d.signals)
+		if cerr := conn.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+
+	return err
+}
+
+// detectScreenLocks starts a ScreenLockDetector on the session and system bus.
+// Failing to connect to one of the buses is not fatal since break detection
+// is only a convenience.
+func (app *App) detectScreenLocks() {
+	var conns []*dbus.Conn
+	for _, connect := range []func(...dbus.ConnOption) (*dbus.Conn, error){
+		dbus.ConnectSessionBus,
+		dbus.ConnectSystemBus,
+	} {
+		conn, err := connect()
+		if err != nil {
+			app.logger.Warn("Failed to connect to D-Bus", zap.Error(err))
+			continue
+		}
+		conns = append(conns, conn)
+	}
+
+	if len(conns) == 0 {
+		return
+	}
+
+	d, err := NewScreenLockDetector(app.breaks, app.logger, conns...)
+	if err != nil {
+		app.logger.Error("Failed to start screen lock detection", zap.Error(err))
+		for _, conn := range conns {
+			conn.Close()
+		}
+		return
+	}
+
+	app.detectors = append(app.detectors, d)
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"go.uber.org/zap"
+)
+
+func TestScreenLockEvent(t *testing.T) {
+	tests := []struct {
+		name   string
+		signal *dbus.Signal
+		ok     bool
+		start  bool
+	}{
+		{
+			name:   "GNOME screen saver activated",
+			signal: &dbus.Signal{Name: "org.gnome.ScreenSaver.ActiveChanged", Body: []interface{}{true}},
+			ok:     true, start: true,
+		},
+		{
+			name:   "GNOME screen saver deactivated",
+			signal: &dbus.Signal{Name: "org.gnome.ScreenSaver.ActiveChanged", Body: []interface{}{false}},
+			ok:     true, start: false,
+		},
+		{
+			name:   "freedesktop screen saver activated",
+			signal: &dbus.Signal{Name: "org.freedesktop.ScreenSaver.ActiveChanged", Body: []interface{}{true}},
+			ok:     true, start: true,
+		},
+		{
+			name:   "logind session locked",
+			signal: &dbus.Signal{Name: "org.freedesktop.login1.Session.Lock"},
+			ok:     true, start: true,
+		},
+		{
+			name:   "logind session unlocked",
+			signal: &dbus.Signal{Name: "org.freedesktop.login1.Session.Unlock"},
+			ok:     true, start: false,
+		},
+		{
+			name:   "unrelated logind signal",
+			signal: &dbus.Signal{Name: "org.freedesktop.login1.Session.PauseDevice", Body: []interface{}{uint32(1), uint32(2), "pause"}},
+		},
+		{
+			name:   "unrelated interface",
+			signal: &dbus.Signal{Name: "org.freedesktop.DBus.NameAcquired", Body: []interface{}{":1.42"}},
+		},
+		{
+			name:   "screen saver signal without body",
+			signal: &dbus.Signal{Name: "org.gnome.ScreenSaver.ActiveChanged"},
+		},
+		{
+			name:   "screen saver signal with wrong type",
+			signal: &dbus.Signal{Name: "org.gnome.ScreenSaver.ActiveChanged", Body: []interface{}{"yes"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, ok := screenLockEvent(tt.signal)
+			if ok != tt.ok {
+				t.Fatalf("screenLockEvent() ok = %v, want %v", ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if e.Start != tt.start {
+				t.Errorf("screenLockEvent() Start = %v, want %v", e.Start, tt.start)
+			}
+			if e.Source != "screen_lock" {
+				t.Errorf("screenLockEvent() Source = %q, want %q", e.Source, "screen_lock")
+			}
+			if e.Time.IsZero() {
+				t.Error("screenLockEvent() did not set the time")
+			}
+		})
+	}
+}
+
+// fakeLogind implements the method of org.freedesktop.login1.Manager which is
+// used to find the session of the process.
+type fakeLogind struct {
+	session dbus.ObjectPath
+}
+
+func (f fakeLogind) GetSessionByPID(pid uint32) (dbus.ObjectPath, *dbus.Error) {
+	return f.session, nil
+}
+
+func TestScreenLockDetector(t *testing.T) {
+	type signal struct {
+		path dbus.ObjectPath
+		name string
+		body []interface{}
+	}
+
+	const (
+		ownSession   = dbus.ObjectPath("/org/freedesktop/login1/session/c1")
+		otherSession = dbus.ObjectPath("/org/freedesktop/login1/session/c2")
+	)
+
+	tests := []struct {
+		name    string
+		session dbus.ObjectPath // empty if logind does not know the session
+		signals []signal
+		start   bool // of the first event
+	}{
+		{
+			name:    "screen saver activated",
+			signals: []signal{{"/org/gnome/ScreenSaver", "org.gnome.ScreenSaver.ActiveChanged", []interface{}{true}}},
+			start:   true,
+		},
+		{
+			name: "logind is ignored if the session is unknown",
+			signals: []signal{
+				{otherSession, "org.freedesktop.login1.Session.Lock", nil},
+				{"/org/freedesktop/ScreenSaver", "org.freedesktop.ScreenSaver.ActiveChanged", []interface{}{false}},
+			},
+			start: false,
+		},
+		{
+			name:    "locks of other sessions are ignored",
+			session: ownSession,
+			signals: []signal{
+				{otherSession, "org.freedesktop.login1.Session.Lock", nil},
+				{ownSession, "org.freedesktop.login1.Session.Unlock", nil},
+			},
+			start: false,
+		},
+		{
+			name:    "lock of the own session",
+			session: ownSession,
+			signals: []signal{{ownSession, "org.freedesktop.login1.Session.Lock", nil}},
+			start:   true,
+		},
+	}
+
+	sessionID, ok := os.LookupEnv("XDG_SESSION_ID")
+	os.Unsetenv("XDG_SESSION_ID")
+	if ok {
+		defer os.Setenv("XDG_SESSION_ID", sessionID)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, stop := startPrivateBus(t)
+			defer stop()
+
+			sender, err := dbus.Connect(addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer sender.Close()
+
+			if tt.session != "" {
+				err = sender.Export(fakeLogind{session: tt.session}, "/org/freedesktop/login1", "org.freedesktop.login1.Manager")
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				reply, err := sender.RequestName("org.freedesktop.login1", dbus.NameFlagDoNotQueue)
+				if err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+					t.Fatalf("failed to own name: %v (reply %d)", err, reply)
+				}
+			}
+
+			conn, err := dbus.Connect(addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			events := make(chan BreakEvent, len(tt.signals))
+			d, err := NewScreenLockDetector(events, zap.NewNop(), conn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer d.Close()
+
+			for _, sig := range tt.signals {
+				err = sender.Emit(sig.path, sig.name, sig.body...)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// The signals of a connection arrive in order, so any signal
+			// which should have been ignored would be the first event.
+			select {
+			case e := <-events:
+				if e.Source != "screen_lock" || e.Start != tt.start {
+					t.Errorf("got event %+v, want start = %v", e, tt.start)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("no break event was received")
+			}
+		})
+	}
+}
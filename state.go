@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/faiface/pixel"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// State is the part of the configuration which changes while you are working.
+// It is stored separately from the settings so the settings file is only
+// written by the user.
+type State struct {
+	CheckIn    time.Time `yaml:"check_in"`
+	Breaks     []Break   `yaml:"breaks,omitempty"`
+	CheckedOut time.Time `yaml:"checked_out,omitempty"` // set if the day was finished manually
+	LastSeen   time.Time `yaml:"last_seen,omitempty"`   // last time the running widget saved the state (see App.updateLastSeen)
+	WindowPos  pixel.Vec `yaml:"window_pos"`
+}
+
+// defaultConfigPath returns $XDG_CONFIG_HOME/go-home/config.yml.
+func defaultConfigPath() string {
+	return filepath.Join(xdgDir("XDG_CONFIG_HOME", ".config"), "go-home", "config.yml")
+}
+
+// defaultStatePath returns $XDG_STATE_HOME/go-home/state.yml.
+func defaultStatePath() string {
+	return filepath.Join(xdgDir("XDG_STATE_HOME", ".local/state"), "go-home", "state.yml")
+}
+
+// xdgDir returns the directory of the given XDG environment variable or its
+// default relative to the home directory.
+func xdgDir(env, fallback string) string {
+	if dir := os.Getenv(env); dir != "" {
+		return dir
+	}
+
+	return filepath.Join(os.Getenv("HOME"), fallback)
+}
+
+// legacyConfigPath is where all settings and the state were stored before
+// they were split into separate files.
+func legacyConfigPath() string {
+	return filepath.Join(os.Getenv("HOME"), ".go-home.yml")
+}
+
+// legacyJournalPath is where the journal was stored before it was moved into
+// the state directory.
+func legacyJournalPath() string {
+	return filepath.Join(xdgDir("XDG_DATA_HOME", ".local/share"), "go-home", "log.jsonl")
+}
+
+// SaveState writes the state file. Most changes while running only affect the
+// state so there is no need to write the settings file as well. The state file
+// is only written by Go Home, so unlike the settings it is encoded from scratch
+// and values which were reset (e.g. checked_out) are removed.
+func (conf Config) SaveState() error {
+	conf.WindowPos.X = math.Round(conf.WindowPos.X)
+	conf.WindowPos.Y = math.Round(conf.WindowPos.Y)
+
+	data, err := yaml.Marshal(conf.State)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode state as YAML")
+	}
+
+	err = writeFileAtomic(conf.statePath, data, 0644)
+	if err != nil {
+		return errors.Wrap(err, "failed to save state")
+	}
+
+	return nil
+}
+
+// migrateLegacyConfig splits a configuration file of an older version into
+// the settings and the state file. Nothing happens if the settings file
+// already exists. The old file is renamed so it is not migrated twice.
+func migrateLegacyConfig(logger *zap.Logger, legacyPath, path, statePath string) error {
+	data, err := ioutil.ReadFile(legacyPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to read old config file")
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		return nil // already migrated
+	}
+
+	logger.Info("Migrating old configuration file",
+		zap.String("from", legacyPath),
+		zap.String("config", path),
+		zap.String("state", statePath),
+	)
+
+	// The old file contains the fields of both files so we decode it twice
+	// and ignore unknown fields each time.
+	var conf Config
+	err = yaml.NewDecoder(bytes.NewReader(data)).Decode(&conf)
+	if err != nil && err != io.EOF {
+		return errors.Wrap(err, "failed to decode old config file")
+	}
+
+	var legacy struct {
+		State `yaml:",inline"`
+		UI    struct {
+			Pos pixel.Vec `yaml:"pos"`
+		} `yaml:"ui"`
+	}
+	err = yaml.NewDecoder(bytes.NewReader(data)).Decode(&legacy)
+	if err != nil && err != io.EOF {
+		return errors.Wrap(err, "failed to decode old config file")
+	}
+
+	conf.Version = configVersion
+	conf.path, conf.statePath = path, statePath
+	conf.State = legacy.State
+	conf.WindowPos = legacy.UI.Pos
+
+	err = conf.Save()
+	if err != nil {
+		return err
+	}
+
+	return errors.Wrap(os.Rename(legacyPath, legacyPath+".migrated"), "failed to rename old config file")
+}
+
+// migrateLegacyJournal moves the journal from the data into the state
+// directory if there is no journal in the state directory yet.
+func migrateLegacyJournal(logger *zap.Logger, legacyPath, path string) error {
+	if _, err := os.Stat(legacyPath); err != nil {
+		return nil
+	}
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+
+	logger.Info("Moving journal", zap.String("from", legacyPath), zap.String("to", path))
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return errors.Wrap(err, "failed to create journal directory")
+	}
+
+	return errors.Wrap(os.Rename(legacyPath, path), "failed to move journal")
+}
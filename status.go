@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// Status is a snapshot of the current work day.
+type Status struct {
+	CheckIn   time.Time `json:"check_in"`
+	CheckOut  time.Time `json:"check_out"`
+	EndOfDay  time.Time `json:"end_of_day"`
+	Remaining Duration  `json:"remaining"` // negative when working overtime
+	Overtime  Duration  `json:"overtime"`
+	Progress  float64   `json:"progress"`          // between check-in (0) and end of day (1)
+	Balance   Duration  `json:"balance"`           // only set if flexitime is enabled
+	DayOff    string    `json:"day_off,omitempty"` // name of the holiday
+}
+
+const defaultStatusFormat = `{{ if .DayOff }}Day off: {{ .DayOff }}
+{{ else }}Check-in:  {{ clock .CheckIn }}
+Check-out: {{ clock .CheckOut }}
+Remaining: {{ duration .Remaining }}
+Progress:  {{ percent .Progress }}
+{{ end }}`
+
+var statusFuncs = template.FuncMap{
+	"clock":    formatClock,
+	"duration": func(d Duration) string { return formatDuration(time.Duration(d)) },
+	"percent":  func(p float64) string { return fmt.Sprintf("%.0f%%", 100*p) },
+}
+
+func NewStatus(conf Config, now time.Time) Status {
+	s := Status{
+		CheckIn:   conf.CheckIn,
+		CheckOut:  conf.CheckOut,
+		EndOfDay:  conf.EndOfDay,
+		Remaining: Duration(conf.CheckOut.Sub(now).Round(time.Second)),
+		Progress:  progress(conf.CheckIn, conf.EndOfDay, now),
+		DayOff:    conf.Today.Holiday,
+	}
+
+	if now.After(conf.CheckOut) {
+		s.Overtime = Duration(now.Sub(conf.CheckOut).Round(time.Second))
+	}
+
+	if conf.Flexitime.Enabled {
+		s.Balance = Duration(conf.Balance)
+	}
+
+	return s
+}
+
+func (app *App) statusCommand() *cobra.Command {
+	var (
+		format  string
+		useJSON bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Print the status of the current work day without opening a window",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if app.initErr != nil {
+				return app.initErr
+			}
+
+			status := NewStatus(app.conf, time.Now())
+			if useJSON {
+				return json.NewEncoder(cmd.OutOrStdout()).Encode(status)
+			}
+
+			return writeStatus(cmd.OutOrStdout(), format, status)
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&format, "format", "", "Go template used to print the status (e.g. '{{ clock .CheckOut }}')")
+	flags.BoolVar(&useJSON, "json", false, "print the status as JSON")
+
+	return cmd
+}
+
+func writeStatus(w io.Writer, format string, status Status) error {
+	if format == "" {
+		format = defaultStatusFormat
+	}
+
+	tmpl, err := template.New("status").Funcs(statusFuncs).Parse(format)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse --format template")
+	}
+
+	return tmpl.Execute(w, status)
+}
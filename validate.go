@@ -0,0 +1,273 @@
+package main
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configProblem is a single problem of the settings file.
+type configProblem struct {
+	Line    int    // 0 if the problem is not caused by a specific line
+	Path    string // dot separated path of the setting, e.g. "ui.fps"
+	Message string
+}
+
+func (p configProblem) String() string {
+	msg := p.Message
+	if p.Path != "" {
+		msg = p.Path + ": " + msg
+	}
+	if p.Line > 0 {
+		msg = fmt.Sprintf("line %d: %s", p.Line, msg)
+	}
+
+	return msg
+}
+
+// configError contains all problems which were found in the settings file.
+type configError []configProblem
+
+func (e configError) Error() string {
+	if len(e) == 1 {
+		return "invalid configuration: " + e[0].String()
+	}
+
+	msg := "invalid configuration:"
+	for _, p := range e {
+		msg += "\n  " + p.String()
+	}
+
+	return msg
+}
+
+// configChecker collects all problems of the settings file instead of
+// stopping at the first one. The YAML document is used to look up the line of
+// each setting.
+type configChecker struct {
+	root     *yaml.Node // mapping node of the settings file or nil
+	problems []configProblem
+}
+
+// add reports a problem of the setting at the given path.
+func (c *configChecker) add(path, format string, args ...interface{}) {
+	var line int
+	if key, _ := findYAMLPath(c.root, path); key != nil {
+		line = key.Line
+	}
+
+	c.problems = append(c.problems, configProblem{
+		Line:    line,
+		Path:    path,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (c *configChecker) notNegative(path string, d time.Duration) {
+	if d < 0 {
+		c.add(path, "%s must not be negative", d)
+	}
+}
+
+// err returns all problems ordered by line or nil if there are none.
+func (c *configChecker) err() error {
+	if len(c.problems) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(c.problems, func(i, j int) bool {
+		return c.problems[i].Line < c.problems[j].Line
+	})
+
+	return configError(c.problems)
+}
+
+var (
+	yamlUnmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// checkNode compares the YAML node with the Go type it is decoded into. It
+// reports unknown keys (only if strict is set) and values which cannot be
+// decoded. Such values are replaced by null so the remaining document can
+// still be decoded to find more problems.
+func (c *configChecker) checkNode(path string, n *yaml.Node, t reflect.Type, strict bool) {
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	if n.Kind == yaml.ScalarNode && n.Tag == "!!null" {
+		return
+	}
+
+	ptr := reflect.PtrTo(t)
+	leaf := ptr.Implements(yamlUnmarshalerType) || ptr.Implements(textUnmarshalerType)
+	switch {
+	case !leaf && t.Kind() == reflect.Ptr:
+		c.checkNode(path, n, t.Elem(), strict)
+	case !leaf && t.Kind() == reflect.Struct && n.Kind == yaml.MappingNode:
+		c.checkMapping(path, n, t, strict)
+	case !leaf && t.Kind() == reflect.Slice && n.Kind == yaml.SequenceNode:
+		for i, item := range n.Content {
+			c.checkNode(joinPath(path, fmt.Sprint(i)), item, t.Elem(), strict)
+		}
+	default:
+		err := n.Decode(reflect.New(t).Interface())
+		if err == nil {
+			return
+		}
+
+		msg := "expected " + describeType(t)
+		if n.Kind == yaml.ScalarNode {
+			if _, ok := err.(*yaml.TypeError); ok {
+				msg = fmt.Sprintf("invalid value %q, %s", n.Value, msg)
+			} else {
+				msg = fmt.Sprintf("invalid value %q: %v", n.Value, err)
+			}
+		}
+
+		c.problems = append(c.problems, configProblem{Line: n.Line, Path: path, Message: msg})
+		*n = yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Line: n.Line, Column: n.Column}
+	}
+}
+
+func (c *configChecker) checkMapping(path string, n *yaml.Node, t reflect.Type, strict bool) {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("yaml"), ",")[0]
+		if f.PkgPath != "" || name == "-" || name == "" {
+			continue
+		}
+		fields[name] = f.Type
+	}
+
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, value := n.Content[i], n.Content[i+1]
+		ft, ok := fields[key.Value]
+		switch {
+		case ok:
+			c.checkNode(joinPath(path, key.Value), value, ft, strict)
+		case strict:
+			c.problems = append(c.problems, configProblem{
+				Line:    key.Line,
+				Path:    joinPath(path, key.Value),
+				Message: "unknown setting",
+			})
+		}
+	}
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+
+	return path + "." + name
+}
+
+// describeType returns what a YAML value of type t looks like.
+func describeType(t reflect.Type) string {
+	switch t {
+	case reflect.TypeOf(time.Duration(0)):
+		return "a duration like 1h30m"
+	case reflect.TypeOf(ClockTime{}):
+		return "a time like 17:30"
+	case reflect.TypeOf(Commands{}):
+		return "a command or a list of commands"
+	case reflect.TypeOf(Holiday{}):
+		return "a date or a mapping"
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	case reflect.Slice:
+		return "a list"
+	case reflect.Struct, reflect.Map:
+		return "a mapping"
+	default:
+		return t.String()
+	}
+}
+
+// check reports invalid values of the settings. It must be called after the
+// defaults have been applied.
+func (conf Config) check(c *configChecker) {
+	c.notNegative("work_duration", conf.WorkDuration)
+	c.notNegative("lunch_duration", conf.LunchDuration)
+	c.notNegative("idle_threshold", conf.IdleThreshold)
+	if conf.DayEnd.minutes() > 24*60 {
+		c.add("day_end", "%s must not be after 24:00", conf.DayEnd)
+	}
+	if conf.DayBoundary.Hour >= 24 {
+		c.add("day_boundary", "%s must be before 24:00", conf.DayBoundary)
+	}
+
+	switch conf.Overnight {
+	case OvernightNewDay, OvernightAfterHours, OvernightPreviousDay:
+	default:
+		c.add("overnight", "unknown mode %q, expected %s, %s or %s", conf.Overnight,
+			OvernightNewDay, OvernightAfterHours, OvernightPreviousDay)
+	}
+
+	for _, day := range []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday} {
+		wd := conf.Schedule.For(day)
+		if wd == nil {
+			continue
+		}
+
+		path := "schedule." + strings.ToLower(day.String())
+		c.notNegative(path+".work_duration", wd.WorkDuration)
+		c.notNegative(path+".lunch_duration", wd.LunchDuration)
+		if wd.DayEnd.minutes() > 24*60 {
+			c.add(path+".day_end", "%s must not be after 24:00", wd.DayEnd)
+		}
+	}
+
+	if conf.UI.FPS < 0 {
+		c.add("ui.fps", "%d must not be negative", conf.UI.FPS)
+	}
+	if conf.UI.WindowWidth < 0 {
+		c.add("ui.width", "%d must not be negative", conf.UI.WindowWidth)
+	}
+	if conf.UI.WindowHeight < 0 {
+		c.add("ui.height", "%d must not be negative", conf.UI.WindowHeight)
+	}
+
+	conf.Notifications.check(c)
+	conf.Hooks.check(c)
+	conf.API.check(c)
+}
+
+// checkIneffective reports settings which are ignored because they depend on
+// another setting which is disabled.
+func (conf Config) checkIneffective(c *configChecker) {
+	if conf.IdleThreshold > 0 && !conf.DetectBreaks {
+		c.add("idle_threshold", "has no effect unless detect_breaks is enabled")
+	}
+}
+
+// checkDay reports settings which do not fit the current day. These are not
+// errors since the day may have started unusually late.
+func (conf Config) checkDay(c *configChecker) {
+	if !conf.EndOfDay.Before(conf.CheckIn) {
+		return
+	}
+
+	path := "day_end"
+	day := conf.WorkDate(conf.CheckIn).Weekday()
+	if wd := conf.Schedule.For(day); wd != nil && wd.DayEnd != (ClockTime{}) {
+		path = "schedule." + strings.ToLower(day.String()) + ".day_end"
+	}
+
+	c.add(path, "%s is before today's check-in at %s", conf.Today.DayEnd, conf.CheckIn.Format("15:04"))
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// watchConfig reloads the configuration whenever the configuration file is
+// changed. We watch the parent directory instead of the file itself because
+// many editors replace the file when saving it.
+func (app *App) watchConfig() (*fsnotify.Watcher, error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create file watcher")
+	}
+
+	path := filepath.Clean(app.conf.path)
+	err = w.Add(filepath.Dir(path))
+	if err != nil {
+		w.Close()
+		return nil, errors.Wrap(err, "failed to watch config directory")
+	}
+
+	go func() {
+		// Editors often emit multiple events when saving a file so we wait
+		// until the file was not touched for a short moment.
+		const delay = 200 * time.Millisecond
+		timer := time.NewTimer(delay)
+		timer.Stop()
+
+		for {
+			select {
+			case e, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(e.Name) == path && e.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) != 0 {
+					timer.Reset(delay)
+				}
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+				app.logger.Warn("Error while watching configuration file", zap.Error(err))
+			case <-timer.C:
+				// An invalid file is only logged so the previous configuration
+				// stays in effect until the file is fixed.
+				if err := app.do(app.reload); err != nil {
+					app.logger.Error("Failed to reload configuration", zap.Error(err))
+				}
+			}
+		}
+	}()
+
+	return w, nil
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// marshalYAMLInto encodes v as YAML like yaml.Marshal but keeps the comments,
+// key order and quoting of the file at path. Only the values which changed are
+// replaced and new keys are inserted. Keys which v does not contain are never
+// removed since they may be unknown to this version of Go Home or were
+// omitted because they are empty. If prepare is not nil, it is applied to the
+// existing document first (e.g. to migrate it). If the file does not exist or
+// cannot be parsed, v is encoded from scratch. The settings at the keep paths
+// (e.g. "ui.width") are not taken from v but keep the value of the file or are
+// left out if the file does not contain them.
+func marshalYAMLInto(path string, v interface{}, prepare func(root *yaml.Node), keep ...string) ([]byte, error) {
+	var update yaml.Node
+	err := update.Encode(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc *yaml.Node
+	if existing, err := ioutil.ReadFile(path); err == nil {
+		doc, _ = parseYAMLDocument(existing)
+	}
+	if doc != nil && prepare != nil {
+		prepare(doc.Content[0])
+	}
+
+	for _, p := range keep {
+		var value *yaml.Node
+		if doc != nil {
+			_, value = findYAMLPath(doc.Content[0], p)
+		}
+
+		if value == nil {
+			deleteYAMLPath(&update, p)
+			continue
+		}
+
+		kept := *value // a copy since the node is still part of doc
+		err = setYAMLPath(&update, p, &kept)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if doc == nil {
+		return yaml.Marshal(&update)
+	}
+
+	mergeYAMLNode(doc.Content[0], &update)
+	return encodeYAMLDocument(doc)
+}
+
+// parseYAMLDocument decodes data into a document node. Use
+// encodeYAMLDocument to write it with the same layout again.
+func parseYAMLDocument(data []byte) (*yaml.Node, error) {
+	var doc yaml.Node
+	err := yaml.Unmarshal(data, &doc)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return nil, errors.New("document is empty")
+	}
+
+	restoreBlankLines(doc.Content[0], strings.Split(string(data), "\n"))
+	return &doc, nil
+}
+
+// encodeYAMLDocument encodes the document with the indentation it was
+// written with.
+func encodeYAMLDocument(doc *yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(yamlIndent(doc.Content[0]))
+	err := enc.Encode(doc)
+	if err == nil {
+		err = enc.Close()
+	}
+
+	return buf.Bytes(), err
+}
+
+// restoreBlankLines keeps the empty lines in front of mapping keys. The YAML
+// parser drops them but the encoder writes a leading empty line of a head
+// comment as is.
+func restoreBlankLines(n *yaml.Node, lines []string) {
+	for i, c := range n.Content {
+		restoreBlankLines(c, lines)
+		if n.Kind != yaml.MappingNode || i%2 != 0 || i == 0 {
+			continue
+		}
+
+		above := c.Line - 1 // the line in front of the key (1-based)
+		if c.HeadComment != "" {
+			above -= strings.Count(c.HeadComment, "\n") + 1
+		}
+		if above > 0 && above <= len(lines) && strings.TrimSpace(lines[above-1]) == "" {
+			c.HeadComment = "\n" + c.HeadComment
+		}
+	}
+}
+
+// yamlIndent returns the indentation of the first nested block mapping or 4,
+// the default of the encoder.
+func yamlIndent(n *yaml.Node) int {
+	if indent := nestedIndent(n); indent > 0 {
+		return indent
+	}
+
+	return 4
+}
+
+func nestedIndent(n *yaml.Node) int {
+	if n.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			if value.Kind == yaml.MappingNode && value.Style&yaml.FlowStyle == 0 && value.Line > key.Line {
+				return value.Column - key.Column
+			}
+		}
+	}
+
+	for _, c := range n.Content {
+		if indent := nestedIndent(c); indent > 0 {
+			return indent
+		}
+	}
+
+	return 0
+}
+
+// mergeYAMLNode updates dst in place so it has the values of src.
+func mergeYAMLNode(dst, src *yaml.Node) {
+	switch {
+	case dst.Kind == yaml.MappingNode && src.Kind == yaml.MappingNode:
+		mergeYAMLMapping(dst, src)
+	case dst.Kind == yaml.SequenceNode && src.Kind == yaml.SequenceNode:
+		for i, item := range src.Content {
+			if i < len(dst.Content) {
+				mergeYAMLNode(dst.Content[i], item)
+			} else {
+				dst.Content = append(dst.Content, item)
+			}
+		}
+		if len(dst.Content) > len(src.Content) {
+			dst.Content = dst.Content[:len(src.Content)]
+		}
+		if len(dst.Content) == 0 {
+			dst.Style = src.Style // an empty block sequence is written as []
+		}
+	case dst.Kind == yaml.ScalarNode && src.Kind == yaml.ScalarNode:
+		// The value of dst was decoded into v, so if it is written the same
+		// way it is kept as is even if the tag differs (e.g. an unquoted date
+		// is a !!timestamp but encoded as !!str).
+		if dst.Value == src.Value || sameDuration(dst.Value, src.Value) {
+			return
+		}
+		if dst.Tag != src.Tag || dst.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+			dst.Style = src.Style
+		}
+		dst.Tag, dst.Value = src.Tag, src.Value
+	default:
+		// The type changed, e.g. from a scalar to a mapping. Replace the node
+		// but keep the comments attached to it.
+		head, line, foot := dst.HeadComment, dst.LineComment, dst.FootComment
+		*dst = *src
+		dst.HeadComment, dst.LineComment, dst.FootComment = head, line, foot
+	}
+}
+
+func mergeYAMLMapping(dst, src *yaml.Node) {
+	values := make(map[string]*yaml.Node, len(src.Content)/2)
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		values[src.Content[i].Value] = src.Content[i+1]
+	}
+
+	seen := make(map[string]bool, len(values))
+	for i := 0; i+1 < len(dst.Content); i += 2 {
+		key := dst.Content[i].Value
+		if update, ok := values[key]; ok && !seen[key] {
+			seen[key] = true
+			mergeYAMLNode(dst.Content[i+1], update)
+		}
+	}
+	content := dst.Content
+
+	// New keys are inserted after the key which precedes them in src so
+	// related settings stay together.
+	pos := 0
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key := src.Content[i].Value
+		if seen[key] {
+			pos = yamlKeyIndex(content, key) + 2
+			continue
+		}
+
+		content = append(content[:pos], append([]*yaml.Node{src.Content[i], src.Content[i+1]}, content[pos:]...)...)
+		pos += 2
+	}
+
+	dst.Content = content
+	if len(content) == 0 {
+		dst.Style = src.Style // an empty block mapping is written as {}
+	}
+}
+
+// yamlKeyIndex returns the index of the given key in the content of a mapping
+// node or -1.
+func yamlKeyIndex(content []*yaml.Node, key string) int {
+	for i := 0; i+1 < len(content); i += 2 {
+		if content[i].Value == key {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// sameDuration returns true if a and b are the same duration written
+// differently, e.g. "8h" and "8h0m0s".
+func sameDuration(a, b string) bool {
+	x, err := time.ParseDuration(a)
+	if err != nil {
+		return false
+	}
+
+	y, err := time.ParseDuration(b)
+	return err == nil && x == y
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type mergeTestSettings struct {
+	Version int            `yaml:"version"`
+	Work    time.Duration  `yaml:"work"`
+	Lunch   time.Duration  `yaml:"lunch"`
+	Days    []string       `yaml:"days,omitempty"`
+	UI      mergeTestUI    `yaml:"ui"`
+	Friday  *mergeTestWeek `yaml:"friday,omitempty"`
+}
+
+type mergeTestUI struct {
+	Width int  `yaml:"width"`
+	FPS   int  `yaml:"fps,omitempty"`
+	Hide  bool `yaml:"hide,omitempty"`
+}
+
+type mergeTestWeek struct {
+	Work time.Duration `yaml:"work,omitempty"`
+	Off  bool          `yaml:"off,omitempty"`
+}
+
+func TestMarshalYAMLInto(t *testing.T) {
+	defaults := mergeTestSettings{Version: 1, Work: 8 * time.Hour, Lunch: time.Hour, UI: mergeTestUI{Width: 512}}
+	with := func(fn func(s *mergeTestSettings)) mergeTestSettings {
+		s := defaults
+		fn(&s)
+		return s
+	}
+
+	tests := []struct {
+		name    string
+		file    string // empty if the file does not exist
+		v       mergeTestSettings
+		prepare func(root *yaml.Node)
+		keep    []string
+		want    string
+	}{
+		{
+			name: "no file",
+			v:    defaults,
+			want: "version: 1\nwork: 8h0m0s\nlunch: 1h0m0s\nui:\n    width: 512\n",
+		},
+		{
+			name: "file cannot be parsed",
+			file: "work: [8h\n",
+			v:    defaults,
+			want: "version: 1\nwork: 8h0m0s\nlunch: 1h0m0s\nui:\n    width: 512\n",
+		},
+		{
+			name: "comments, order and blank lines are kept",
+			file: "# my settings\nlunch: 1h # long lunch\n\nversion: 1\n\n# full day\nwork: 8h\nui:\n  width: 512\n",
+			v:    with(func(s *mergeTestSettings) { s.Lunch = 30 * time.Minute }),
+			want: "# my settings\nlunch: 30m0s # long lunch\n\nversion: 1\n\n# full day\nwork: 8h\nui:\n  width: 512\n",
+		},
+		{
+			name: "unknown keys of newer versions are kept",
+			file: "version: 2\nwork: 8h\nlunch: 1h\nfuture_setting: 42 # keep me\nui:\n    width: 512\n    theme: dark\n",
+			v:    with(func(s *mergeTestSettings) { s.Version = 2; s.UI.Width = 600 }),
+			want: "version: 2\nwork: 8h\nlunch: 1h\nfuture_setting: 42 # keep me\nui:\n    width: 600\n    theme: dark\n",
+		},
+		{
+			name: "empty values written by the user are kept",
+			file: "version: 1\nwork: 8h\nlunch: 1h\nui:\n    width: 512\nfriday:\n    # short\n    work: 6h\n    off: false\n",
+			v:    with(func(s *mergeTestSettings) { s.Friday = &mergeTestWeek{Work: 6 * time.Hour} }),
+			want: "version: 1\nwork: 8h\nlunch: 1h\nui:\n    width: 512\nfriday:\n    # short\n    work: 6h\n    off: false\n",
+		},
+		{
+			name: "new keys are inserted after their predecessor",
+			file: "version: 1\nwork: 8h\nui:\n    width: 512\n",
+			v:    with(func(s *mergeTestSettings) { s.UI.FPS = 30 }),
+			want: "version: 1\nwork: 8h\nlunch: 1h0m0s\nui:\n    width: 512\n    fps: 30\n",
+		},
+		{
+			name: "quoting and date tags are kept",
+			file: "version: 1\nwork: \"8h\"\nlunch: 1h\ndays:\n    - 2019-12-25\n    - '2019-12-26'\nui:\n    width: 512\n",
+			v:    with(func(s *mergeTestSettings) { s.Days = []string{"2019-12-25", "2019-12-26"} }),
+			want: "version: 1\nwork: \"8h\"\nlunch: 1h\ndays:\n    - 2019-12-25\n    - '2019-12-26'\nui:\n    width: 512\n",
+		},
+		{
+			name: "sequences grow and shrink",
+			file: "version: 1\nwork: 8h\nlunch: 1h\ndays:\n    - 2019-12-25 # christmas\n    - 2019-12-26\nui:\n    width: 512\n",
+			v:    with(func(s *mergeTestSettings) { s.Days = []string{"2019-12-25"} }),
+			want: "version: 1\nwork: 8h\nlunch: 1h\ndays:\n    - 2019-12-25 # christmas\nui:\n    width: 512\n",
+		},
+		{
+			name: "kept settings are taken from the file",
+			file: "version: 1\nwork: 8h\nlunch: 1h\nui:\n    width: 512\n",
+			v:    with(func(s *mergeTestSettings) { s.UI.Width = 1024; s.UI.FPS = 60 }),
+			keep: []string{"ui.width", "ui.fps"},
+			want: "version: 1\nwork: 8h\nlunch: 1h\nui:\n    width: 512\n",
+		},
+		{
+			name:    "prepare can remove keys",
+			file:    "work: 8h\nlunch: 1h\ncheck_in: 2019-06-17T08:00:00Z\nui:\n    width: 512\n",
+			v:       defaults,
+			prepare: func(root *yaml.Node) { deleteYAMLPath(root, "check_in") },
+			want:    "version: 1\nwork: 8h\nlunch: 1h\nui:\n    width: 512\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "go-home-test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			path := filepath.Join(dir, "config.yml")
+			if tt.file != "" {
+				err = ioutil.WriteFile(path, []byte(tt.file), 0600)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			data, err := marshalYAMLInto(path, tt.v, tt.prepare, tt.keep...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(data) != tt.want {
+				t.Errorf("marshalYAMLInto() =\n%s\nwant\n%s", data, tt.want)
+			}
+		})
+	}
+}